@@ -1,12 +1,14 @@
 import m, {VnodeDOM} from "mithril";
-import {EditorState} from "@codemirror/state";
+import {Compartment, EditorState} from "@codemirror/state";
 import {EditorView} from "@codemirror/view";
-import {PostgreSQL, sql} from "@codemirror/lang-sql";
+import {PostgreSQL, sql, SQLNamespace} from "@codemirror/lang-sql";
 import {defaultEditorExtensions, defaultExtensions} from "./components/editor";
 
 type Props = {
   class?: string,
   value: string,
+  // schema used for completions of tables and columns
+  schema?: SQLNamespace,
   on: {
     update: (value: string) => void,
   },
@@ -14,9 +16,16 @@ type Props = {
 
 export default function(): m.Component<Props, any> {
   let editor: EditorView | null = null;
+  let schema: SQLNamespace | undefined = undefined;
+  const language = new Compartment();
+  const languageExtension = () => sql({
+    dialect: PostgreSQL,
+    schema: schema,
+  });
   return {
     oncreate(vnode: VnodeDOM<Props, any>) {
       const {value, on} = vnode.attrs;
+      schema = vnode.attrs.schema;
 
       if (editor) {
         if (value !== editor.state.doc.toString()) {
@@ -37,9 +46,7 @@ export default function(): m.Component<Props, any> {
         extensions: [
           ...defaultExtensions,
           ...defaultEditorExtensions(on.update),
-          sql({
-            dialect: PostgreSQL,
-          }),
+          language.of(languageExtension()),
         ],
       });
 
@@ -48,6 +55,14 @@ export default function(): m.Component<Props, any> {
         state: state,
       });
      },
+    onupdate(vnode: VnodeDOM<Props, any>) {
+      if (editor === null || vnode.attrs.schema === schema) {
+        return;
+      }
+
+      schema = vnode.attrs.schema;
+      editor.dispatch({effects: language.reconfigure(languageExtension())});
+    },
     onremove() {
       editor?.destroy();
     },
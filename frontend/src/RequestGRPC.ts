@@ -2,8 +2,9 @@ import m, {Vnode} from "mithril";
 import {NInput, NButton, NInputGroup, NSelect} from "./components/input";
 import {NTabs} from "./components/layout";
 import {NTag, NTable, NEmpty} from "./components/dataview";
-import {GRPCCodes} from "./api";
+import {api, GRPCCodes} from "./api";
 import {database} from '../wailsjs/go/models';
+import {EventsOn} from "../wailsjs/runtime/runtime";
 import EditorJSON from "./components/EditorJSON";
 import ViewJSON from "./components/ViewJSON";
 import ParamsList from "./components/ParamsList";
@@ -19,7 +20,20 @@ export default function(id: string) {
   const loadingMethods = false;
   let requestTab = "tab-req-request";
   let responseTab = "tab-resp-body";
+  let live: database.GRPCMessage[] = [];
+  let streaming = false; // NOTE: call is in progress, messages can be sent if it is bidirectional
+  let outgoing = "{}";
+  let offMessages = () => {};
   return {
+    oninit() {
+      offMessages = EventsOn(`grpc:message:${id}`, (msg: database.GRPCMessage) => {
+        live.push(msg);
+        m.redraw();
+      });
+    },
+    onremove() {
+      offMessages();
+    },
     view() {
       // {request, response, is_loading, update_request, send}
       const r = use_request<Request, database.GRPCResponse>(id);
@@ -87,7 +101,14 @@ export default function(id: string) {
           }),
           m(NButton, {
             type: "primary",
-            on: {click: r.send},
+            on: {click: () => {
+              live = [];
+              streaming = true;
+              r.send().then(() => {
+                streaming = false;
+                m.redraw();
+              });
+            }},
             disabled: r.is_loading,
           }, "Send"),
         ]),
@@ -134,6 +155,31 @@ export default function(id: string) {
             },
           ],
         }),
+        streaming ?
+        m("div", {class: "h100", style: {display: "flex", "flex-direction": "column", gap: ".5em", "overflow-y": "auto"}}, [
+          m("div", `Streaming, ${live.length} messages received`),
+          m(EditorJSON, {
+            value: outgoing,
+            on: {update: (payload: string) => outgoing = payload},
+          }),
+          m(NInputGroup, [
+            m(NButton, {
+              on: {click: async () => {
+                const res = await api.grpcStreamSend(id, outgoing);
+                if (res.kind === "err")
+                  notification.error({title: "Could not send message", content: res.value});
+              }},
+            }, "Send message"),
+            m(NButton, {
+              on: {click: async () => {
+                const res = await api.grpcStreamClose(id);
+                if (res.kind === "err")
+                  notification.error({title: "Could not close stream", content: res.value});
+              }},
+            }, "Close stream"),
+          ]),
+          viewMessages(live),
+        ]) :
         r.response === null ?
         m(NEmpty, {
           description: "Send request or choose one from history.",
@@ -158,26 +204,29 @@ export default function(id: string) {
               style: {"overflow-y": "auto"},
               elem: m(ViewJSON, {value: response?.response}),
             },
+            ...((response.details ?? []).length > 0 ? [{
+              id: "tab-resp-details",
+              name: "Details",
+              style: {"overflow-y": "auto"},
+              elem: m(ViewJSON, {value: `[${response.details.join(",")}]`}),
+            }] : []),
+            ...((response.messages ?? []).length > 1 ? [{
+              id: "tab-resp-messages",
+              name: `Messages (${response.messages.length})`,
+              style: {"overflow-y": "auto"},
+              elem: viewMessages(response.messages),
+            }] : []),
             {
               id: "tab-resp-headers",
               name: "Metadata",
               style: {flex: 1},
-              elem: m(NTable, {striped: true, size: "small", "single-column": true, "single-line": false}, [
-                m("colgroup", [
-                  m("col", {style: {width: "50%"}}),
-                  m("col", {style: {width: "50%"}}),
-                ]),
-                m("thead", [
-                  m("tr", [
-                    m("th", "NAME"),
-                    m("th", "VALUE"),
-                  ]),
-                ]),
-                ...response.metadata.map(header => m("tr", {key: header.key}, [
-                  m("td", header.key),
-                  m("td", header.value),
-                ])),
-              ]),
+              elem: viewMetadata(response.metadata ?? []),
+            },
+            {
+              id: "tab-resp-trailers",
+              name: "Trailers",
+              style: {flex: 1},
+              elem: viewMetadata(response.trailers ?? []),
             },
           ],
         }))(r.response),
@@ -186,6 +235,40 @@ export default function(id: string) {
   };
 }
 
+function viewMessages(messages: database.GRPCMessage[]) {
+  return m(NTable, {striped: true, size: "small", "single-column": true, "single-line": false}, [
+    m("thead", [
+      m("tr", [
+        m("th", "RECEIVED AT"),
+        m("th", "MESSAGE"),
+      ]),
+    ]),
+    ...messages.map((msg, i) => m("tr", {key: i}, [
+      m("td", new Date(msg.received_at).toLocaleTimeString()),
+      m("td", m("code", {style: {"white-space": "pre-wrap"}}, msg.data)),
+    ])),
+  ]);
+}
+
+function viewMetadata(kvs: database.KV[]) {
+  return m(NTable, {striped: true, size: "small", "single-column": true, "single-line": false}, [
+    m("colgroup", [
+      m("col", {style: {width: "50%"}}),
+      m("col", {style: {width: "50%"}}),
+    ]),
+    m("thead", [
+      m("tr", [
+        m("th", "NAME"),
+        m("th", "VALUE"),
+      ]),
+    ]),
+    ...kvs.map(kv => m("tr", {key: kv.key}, [
+      m("td", kv.key),
+      m("td", kv.value),
+    ])),
+  ]);
+}
+
 // <style lang="css" scoped>
 // .n-tab-pane {
 //   height: 100% !important;
@@ -1,13 +1,63 @@
 import m from "mithril";
-import {NInput, NButton, NInputGroup} from "./components/input";
+import {NInput, NButton, NInputGroup, NSelect} from "./components/input";
 import {NEmpty} from "./components/dataview";
 import ViewJSON from "./components/ViewJSON";
 import EditorJSON from "./components/EditorJSON";
 import {database} from "../wailsjs/go/models";
-import {use_request} from "./store";
+import {use_request, store} from "./store";
 
 type Request = {kind: database.Kind.JQ} & database.JQRequest;
 
+// inputOptions lists requests whose responses can be used as input
+function inputOptions(id: string) {
+  return [{label: "Inline JSON", value: ""}].concat(Object.entries(store.requests)
+    .filter(([inputID, preview]) => inputID !== id && preview.Kind !== database.Kind.MD)
+    .map(([inputID]) => ({label: inputID, value: inputID}))
+    .sort((a, b) => a.label.localeCompare(b.label)));
+}
+
+const inputFormatOptions = Object.values(database.JQInputFormat).map(format => ({label: format.toUpperCase(), value: format}));
+const outputFormatOptions = Object.values(database.JQOutputFormat).map(format => ({label: format.toUpperCase(), value: format}));
+
+// viewResponse shows json results with json viewer, other formats as text
+function viewResponse(response: database.JQResponse) {
+  switch (response.format ?? database.JQOutputFormat.JSON) {
+  case database.JQOutputFormat.JSON:
+  case database.JQOutputFormat.COMPACT_JSON:
+    return m(ViewJSON, {value: (response.response ?? []).join("\n")});
+  default:
+    return m("pre", {class: "h100", style: {margin: 0, overflow: "auto"}},
+      (response.response ?? []).join(response.format === database.JQOutputFormat.YAML ? "\n---\n" : "\n"));
+  }
+}
+
+// viewArgs edits named variables, empty last row adds new one
+function viewArgs(args: database.JQArg[], update: (args: database.JQArg[]) => void) {
+  const rows = [...args, {name: "", value: "", json: false}];
+  const set = (i: number, patch: Partial<database.JQArg>) =>
+    update(rows.map((arg, j) => i === j ? {...arg, ...patch} : arg).filter(arg => arg.name !== "" || arg.value !== ""));
+  return m("div", rows.map((arg, i) => m(NInputGroup, {key: i}, [
+    m(NInput, {
+      placeholder: "$name",
+      value: arg.name,
+      on: {update: (name: string) => set(i, {name})},
+    }),
+    m(NInput, {
+      placeholder: arg.json ? "JSON value" : "String value",
+      value: arg.value,
+      on: {update: (value: string) => set(i, {value})},
+    }),
+    m("label", {title: "Parse value as JSON, like --argjson"}, [
+      m("input", {
+        type: "checkbox",
+        checked: arg.json,
+        onchange: (e: Event) => set(i, {json: (e.target as HTMLInputElement).checked}),
+      }),
+      "JSON",
+    ]),
+  ])));
+}
+
 export default function(
   id: string,
   show_request: () => boolean,
@@ -18,7 +68,6 @@ export default function(
       const r = use_request<Request, database.JQResponse>(id);
 
       const jqerror: string | null = null; // TODO: use
-      const responseText = (r.response?.response ?? []).join("\n");
 
       if (r.request === null) {
         return m(NEmpty, {
@@ -44,6 +93,12 @@ export default function(
             value: r.request.query,
             on: {update: (query: string) => r.update_request({query})},
           }),
+          m(NSelect, {
+            value: r.request.output_format ?? database.JQOutputFormat.JSON,
+            options: outputFormatOptions,
+            style: {width: "10%", "min-width": "10em"},
+            on: {update: (output_format: database.JQOutputFormat) => r.update_request({output_format})},
+          }),
           // TODO: autosend
           m(NButton, {
             type: "primary",
@@ -51,11 +106,31 @@ export default function(
             disabled: r.is_loading,
           }, "Send"),
         ]),
-        show_request() && m(EditorJSON, {
-          class: "h100",
-          value: r.request.json,
-          on: {update: (json: string) => r.update_request({json})},
-        }),
+        show_request() && m("div", {class: "h100", style: {display: "flex", "flex-direction": "column", gap: ".5em"}}, [
+          m(NInputGroup, [
+            m(NSelect, {
+              value: r.request.input ?? "",
+              options: inputOptions(id),
+              on: {update: (input: string) => r.update_request({input, input_index: 0})},
+            }),
+            m(NSelect, {
+              value: r.request.input_format ?? database.JQInputFormat.JSON,
+              options: inputFormatOptions,
+              on: {update: (input_format: database.JQInputFormat) => r.update_request({input_format})},
+            }),
+            (r.request.input ?? "") !== "" ? m(NInput, {
+              placeholder: "Response, 0 is latest",
+              value: String(r.request.input_index ?? 0),
+              on: {update: (value: string) => r.update_request({input_index: parseInt(value) || 0})},
+            }) : null,
+          ]),
+          viewArgs(r.request.args ?? [], (args: database.JQArg[]) => r.update_request({args})),
+          (r.request.input ?? "") === "" ? m(EditorJSON, {
+            class: "h100",
+            value: r.request.json,
+            on: {update: (json: string) => r.update_request({json})},
+          }) : null,
+        ]),
         jqerror !== null ?
         m("div", {style: {position: "fixed", color: "red", bottom: "3em"}}, jqerror) :
         r.response === null ?
@@ -64,7 +139,7 @@ export default function(
           class: "h100",
           style: {"justify-content": "center"},
         }) :
-        m(ViewJSON, {value: responseText}),
+        viewResponse(r.response),
       ]);
     },
   };
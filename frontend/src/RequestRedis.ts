@@ -1,15 +1,253 @@
 import m from "mithril";
-import {NButton, NInputGroup, NInput} from "./components/input";
-import {NEmpty} from "./components/dataview";
+import {NButton, NInputGroup, NInput, NSelect} from "./components/input";
+import {NTabs} from "./components/layout";
+import {NEmpty, NTable, NTag} from "./components/dataview";
 import ViewJSON from "./components/ViewJSON";
 import EditorJSON from "./components/EditorJSON";
-import {database} from "../wailsjs/go/models";
-import {use_request} from "./store";
+import {app, database} from "../wailsjs/go/models";
+import {api} from "./api";
+import {use_request, notification} from "./store";
+import {EventsOn} from "../wailsjs/runtime/runtime";
 
 type Request = {kind: database.Kind.REDIS} & database.RedisRequest;
 
+// RESPValue is typed reply tree, see respValue in internal/app/resp.go
+type RESPValue = app.respValue;
+
+function hex(base64: string): string {
+  return Array.from(atob(base64), c => c.charCodeAt(0).toString(16).padStart(2, "0")).join(" ");
+}
+
+function viewRESP(v: RESPValue): m.Children {
+  const tag = m(NTag, {type: v.type === "error" ? "warning" : "info", size: "small", style: {"margin-right": ".5em"}}, v.type);
+  switch (v.type) {
+  case "array":
+  case "set":
+  case "push":
+    return m("div", [
+      m("div", [tag, `${(v.items ?? []).length} items`]),
+      m("ol", {start: 0, style: {margin: 0}}, (v.items ?? []).map(item => m("li", viewRESP(item)))),
+    ]);
+  case "map":
+    return m("div", [
+      m("div", [tag, `${(v.entries ?? []).length} entries`]),
+      m("ul", {style: {margin: 0}}, (v.entries ?? []).map(e => m("li", [viewRESP(e.key), viewRESP(e.value)]))),
+    ]);
+  case "null":
+    return m("div", tag);
+  default:
+    return m("div", [
+      tag,
+      v.format !== undefined ? m("i", `${v.format}: `) : null,
+      v.encoding === "base64" ?
+        m("code", {title: v.value as string}, hex(v.value as string)) :
+        m("code", {style: {"white-space": "pre-wrap", color: v.type === "error" ? "red" : undefined}}, String(v.value)),
+    ]);
+  }
+}
+
+function viewResults(results: database.RedisResult[]) {
+  return m(NTable, {striped: true, size: "small", "single-column": true, "single-line": false}, [
+    m("thead", [
+      m("tr", [
+        m("th", "COMMAND"),
+        m("th", "NODE"),
+        m("th", "LATENCY"),
+        m("th", "REPLY"),
+      ]),
+    ]),
+    ...results.map((r, i) => m("tr", {key: i}, [
+      m("td", m("code", r.command)),
+      m("td", r.node),
+      m("td", r.latency ? `${(r.latency / 1e6).toFixed(3)}ms` : ""),
+      m("td", r.value ? viewRESP(JSON.parse(r.value)) : r.error !== "" ? r.error : r.response),
+    ])),
+  ]);
+}
+
+function formatTTL(ttl: number): string {
+  if (ttl < 0) return "no expiry";
+  return ttl < 1000 ? `${ttl}ms` : `${Math.round(ttl / 1000)}s`;
+}
+
+// keyBrowser lists keys matching pattern page by page and shows value of selected key
+function keyBrowser(id: string) {
+  let pattern = "*";
+  let keys: app.redisKeyInfo[] = [];
+  let cursor = "0";
+  let loading = false;
+  let selected: app.redisKeyInfo | null = null;
+  let page: app.RedisKeyPage | null = null;
+
+  const scan = async (reset: boolean) => {
+    if (reset) {
+      keys = [];
+      cursor = "0";
+    }
+    loading = true;
+    const res = await api.redisScan(id, pattern, cursor);
+    loading = false;
+    if (res.kind === "err") {
+      notification.error({title: "Could not scan keys", content: res.value});
+      return;
+    }
+    keys.push(...(res.value.keys ?? []));
+    cursor = res.value.cursor;
+    m.redraw();
+  };
+
+  const fetch = async (key: app.redisKeyInfo, more: boolean) => {
+    const res = await api.redisKeyValue(id, key, more && page !== null ? page.cursor : "0");
+    if (res.kind === "err") {
+      notification.error({title: `Could not fetch key ${key.key}`, content: res.value});
+      return;
+    }
+    const next = res.value;
+    if (more && page !== null) {
+      next.value.items = [...(page.value.items ?? []), ...(next.value.items ?? [])];
+      next.value.entries = [...(page.value.entries ?? []), ...(next.value.entries ?? [])];
+    }
+    selected = key;
+    page = next;
+    m.redraw();
+  };
+
+  return () => m("div", {class: "h100", style: {display: "flex", "flex-direction": "column", gap: ".5em", overflow: "auto"}}, [
+    m(NInputGroup, [
+      m(NInput, {
+        placeholder: "Pattern",
+        value: pattern,
+        on: {update: (value: string) => pattern = value},
+      }),
+      m(NButton, {on: {click: () => scan(true)}, disabled: loading}, "Scan"),
+    ]),
+    m(NTable, {striped: true, size: "small", "single-column": true, "single-line": false}, [
+      m("thead", [
+        m("tr", [
+          m("th", "KEY"),
+          m("th", "TYPE"),
+          m("th", "TTL"),
+          m("th", "MEMORY"),
+        ]),
+      ]),
+      ...[...keys].sort((a, b) => a.key.localeCompare(b.key)).map(key => m("tr", {
+        key: key.encoding + key.key,
+        style: {cursor: "pointer", "font-weight": key === selected ? "bold" : undefined},
+        onclick: () => fetch(key, false),
+      }, [
+        m("td", m("code", key.encoding === "base64" ? hex(key.key) : key.key)),
+        m("td", key.type),
+        m("td", formatTTL(key.ttl)),
+        m("td", key.memory_usage >= 0 ? `${key.memory_usage}B` : ""),
+      ])),
+    ]),
+    cursor !== "0" ? m(NButton, {on: {click: () => scan(false)}, disabled: loading}, "Load more keys") : null,
+    page === null || selected === null ? null : m("div", [
+      m("div", `${selected.key}: ${page.type}, length ${page.total}`),
+      viewRESP(page.value),
+      page.cursor !== "0" ? m(NButton, {on: {click: () => fetch(selected!, true)}}, "Load more") : null,
+    ]),
+  ]);
+}
+
+const modeOptions = Object.values(database.RedisMode).map(mode => ({label: mode.toUpperCase(), value: mode}));
+const deploymentOptions = Object.values(database.RedisDeploymentKind).map(kind => ({label: kind.toUpperCase(), value: kind}));
+
+// viewDeployment edits how servers are discovered, DSN credentials, db and TLS apply to every node
+function viewDeployment(
+  deployment: database.RedisDeployment,
+  update: (patch: Partial<database.RedisDeployment>) => void,
+) {
+  return m("div", {style: {display: "flex", "flex-direction": "column", gap: ".5em"}}, [
+    m(NSelect, {
+      value: deployment.kind,
+      options: deploymentOptions,
+      on: {update: (kind: database.RedisDeploymentKind) => update({kind})},
+    }),
+    deployment.kind === database.RedisDeploymentKind.SENTINEL ? m(NInput, {
+      placeholder: "Master name",
+      value: deployment.master_name,
+      on: {update: (master_name: string) => update({master_name})},
+    }) : null,
+    deployment.kind !== database.RedisDeploymentKind.STANDALONE ? m(NInput, {
+      placeholder: deployment.kind === database.RedisDeploymentKind.SENTINEL ?
+        "Other sentinels, host:port separated by spaces" :
+        "Other seed nodes, host:port separated by spaces",
+      value: (deployment.nodes ?? []).join(" "),
+      on: {update: (value: string) => update({nodes: value.split(/\s+/).filter(node => node !== "")})},
+    }) : null,
+  ]);
+}
+
+function viewMessages(messages: database.RedisMessage[]) {
+  return m(NTable, {striped: true, size: "small", "single-column": true, "single-line": false}, [
+    m("thead", [
+      m("tr", [
+        m("th", "RECEIVED AT"),
+        m("th", "CHANNEL"),
+        m("th", "PAYLOAD"),
+      ]),
+    ]),
+    ...messages.map((msg, i) => m("tr", {key: i}, [
+      m("td", new Date(msg.received_at).toLocaleTimeString()),
+      m("td", [
+        m("code", msg.channel),
+        msg.pattern !== "" ? m("i", ` (${msg.pattern})`) : null,
+        msg.id !== "" ? m("div", m("code", msg.id)) : null,
+      ]),
+      m("td", m("code", {style: {"white-space": "pre-wrap"}}, msg.payload)),
+    ])),
+  ]);
+}
+
+function viewSubscription(
+  mode: database.RedisMode,
+  sub: database.RedisSubscription,
+  update: (patch: Partial<database.RedisSubscription>) => void,
+) {
+  const streams = mode === database.RedisMode.XREAD || mode === database.RedisMode.XREADGROUP;
+  return m("div", {style: {display: "flex", "flex-direction": "column", gap: ".5em"}}, [
+    m(NInput, {
+      placeholder: mode === database.RedisMode.PSUBSCRIBE ? "Patterns" : streams ? "Streams" : "Channels",
+      value: (sub.channels ?? []).join(" "),
+      on: {update: (value: string) => update({channels: value.split(/\s+/).filter(ch => ch !== "")})},
+    }),
+    mode === database.RedisMode.XREADGROUP ? [
+      m(NInput, {
+        placeholder: "Group",
+        value: sub.group,
+        on: {update: (group: string) => update({group})},
+      }),
+      m(NInput, {
+        placeholder: "Consumer",
+        value: sub.consumer,
+        on: {update: (consumer: string) => update({consumer})},
+      }),
+    ] : null,
+    streams ? m(NInput, {
+      placeholder: mode === database.RedisMode.XREADGROUP ? "Start id, > by default" : "Start id, $ by default",
+      value: sub.start,
+      on: {update: (start: string) => update({start})},
+    }) : null,
+  ]);
+}
+
 export default function(id: string) {
+  let requestTab = "tab-req-query";
+  let responseTab = "tab-resp-body";
+  const viewKeys = keyBrowser(id);
+  let live: database.RedisMessage[] = [];
+  let offMessages = () => {};
   return {
+    oninit() {
+      offMessages = EventsOn(`redis:message:${id}`, (msg: database.RedisMessage) => {
+        live.push(msg);
+        m.redraw();
+      });
+    },
+    onremove() {
+      offMessages();
+    },
     view() {
       // const {id} = vnode.attrs;
       // {request, response, is_loading, update_request, send}
@@ -22,34 +260,123 @@ export default function(id: string) {
           style: {"justify-content": "center"},
         });
 
+      const mode = r.request.mode || database.RedisMode.QUERY;
+      const subscribing = mode !== database.RedisMode.QUERY;
+      const subscription = r.request.subscription ?? {channels: [], group: "", consumer: "", start: ""};
+      const deployment = r.request.deployment ?? {kind: database.RedisDeploymentKind.STANDALONE, master_name: "", nodes: []};
       return m("div", {
         class: "h100",
         style: {display: "grid", "grid-template-columns": "1fr 1fr", "grid-template-rows": "34px 1fr", "grid-column-gap": ".5em"},
       }, [
         m(NInputGroup, {style: {"grid-column": "span 2"}}, [
+          m(NSelect, {
+            value: mode,
+            options: modeOptions,
+            style: {width: "10%", "min-width": "10em"},
+            on: {update: (mode: database.RedisMode) => r.update_request({mode})},
+          }),
           m(NInput, {
             placeholder: "DSN",
             value: r.request.dsn,
             on: {update: (dsn: string) => r.update_request({dsn})},
           }),
+          subscribing && r.is_loading ?
+          m(NButton, {
+            type: "primary",
+            on: {click: async () => {
+              const res = await api.redisSubscriptionStop(id);
+              if (res.kind === "err")
+                notification.error({title: "Could not stop subscription", content: res.value});
+            }},
+          }, "Stop") :
           m(NButton, {
             type: "primary",
-            on: {click: r.send},
+            on: {click: () => {
+              live = [];
+              r.send();
+            }},
             disabled: r.is_loading,
-          }, "Send"),
+          }, subscribing ? "Subscribe" : "Send"),
         ]),
-        m(EditorJSON, {
+        m(NTabs, {
+          type: "line",
+          size: "small",
           class: "h100",
-          value: r.request.query ?? null,
-          on: {update: (value: string) => r.update_request({query: value})},
+          value: requestTab,
+          on: {update: (id: string) => requestTab = id},
+          tabs: [
+            {
+              id: "tab-req-query",
+              name: subscribing ? "Subscription" : "Query",
+              elem: subscribing ?
+                viewSubscription(mode, subscription, patch => r.update_request({subscription: {...subscription, ...patch}})) :
+                m(EditorJSON, {
+                  class: "h100",
+                  value: r.request.query ?? null,
+                  on: {update: (value: string) => r.update_request({query: value})},
+                }),
+            },
+            {
+              id: "tab-req-deployment",
+              name: "Deployment",
+              elem: viewDeployment(deployment, patch => r.update_request({deployment: {...deployment, ...patch}})),
+            },
+            {
+              id: "tab-req-keys",
+              name: "Keys",
+              elem: viewKeys(),
+            },
+          ],
         }),
+        subscribing && r.is_loading ?
+        m("div", {class: "h100", style: {"overflow-y": "auto"}}, [
+          m("div", `Listening, ${live.length} messages received`),
+          viewMessages(live),
+        ]) :
         r.response === null ?
         m(NEmpty, {
           description: "Send request or choose one from history.",
           class: "h100",
           style: {"justify-content": "center"},
         }) :
-        m(ViewJSON, {value: r.response.response}),
+        ((response: database.RedisResponse) => m(NTabs, {
+          type: "card",
+          size: "small",
+          style: {"overflow-y": "auto"},
+          // NOTE: results and messages tabs are exclusive, fall back to body when switching kind of response
+          value: responseTab === "tab-resp-results" && (response.messages ?? []).length > 0 ||
+            responseTab === "tab-resp-messages" && (response.messages ?? []).length === 0 ?
+            "tab-resp-body" :
+            responseTab,
+          on: {update: (id: string) => responseTab = id},
+          tabs: [
+            ...(response.protocol ? [{
+              id: "tab-resp-protocol",
+              name: `RESP${response.protocol}`,
+              disabled: true,
+            }] : []),
+            {
+              id: "tab-resp-body",
+              name: "Body",
+              style: {"overflow-y": "auto"},
+              elem: m(ViewJSON, {value: response.response}),
+            },
+            ...((response.messages ?? []).length > 0 ? [{
+              id: "tab-resp-messages",
+              name: "Messages",
+              style: {"overflow-y": "auto"},
+              elem: [
+                response.error ? m(NTag, {type: "error", size: "small"}, response.error) : null,
+                viewMessages(response.messages),
+              ],
+            }] : [{
+              id: "tab-resp-results",
+              name: "Results",
+              style: {"overflow-y": "auto"},
+              elem: viewResults(response.results ?? []),
+            }]),
+          ],
+        }))(r.response),
       ]);
     },
   };
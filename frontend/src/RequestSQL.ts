@@ -12,11 +12,13 @@ import {NButton, NInput, NInputGroup, NSelect} from "./components/input";
 //   CheckSquareOutlined, ClockCircleOutlined,
 //   FieldNumberOutlined, ItalicOutlined, QuestionCircleOutlined,
 // } from "@vicons/antd"
-import {database} from "../wailsjs/go/models";
+import {app, database} from "../wailsjs/go/models";
 // import {Database} from "./api";
 import EditorSQL from "./EditorSQL";
+import ViewJSON from "./components/ViewJSON";
 import {use_request} from "./store";
-import {Database} from "./api";
+import {api, Database} from "./api";
+import type {SQLNamespace} from "@codemirror/lang-sql";
 
 type Request = {kind: database.Kind.SQL} & database.SQLRequest;
 
@@ -79,11 +81,89 @@ const DataTable = {
   },
 }
 
+function schemaNamespace(resp: app.SQLSchemaResponse): SQLNamespace {
+  const namespace: {[name: string]: {[table: string]: string[]}} = {};
+  const tables: {[table: string]: string[]} = {};
+  for (const schema of resp.schemas ?? []) {
+    namespace[schema.name] = {};
+    for (const table of schema.tables ?? []) {
+      const columns = (table.columns ?? []).map(c => c.name);
+      namespace[schema.name][table.name] = columns;
+      tables[table.name] = columns;
+    }
+  }
+  // NOTE: allow tables without schema prefix
+  return {...namespace, ...tables};
+}
+
+const paramTypeOptions = Object.values(database.SQLParamType).map(type => ({label: type.toUpperCase(), value: type}));
+
+// viewParams edits bind params, empty last row adds new one
+function viewParams(params: database.SQLParam[], update: (params: database.SQLParam[]) => void) {
+  const rows = [...params, {name: "", type: database.SQLParamType.STRING, value: ""}];
+  const set = (i: number, patch: Partial<database.SQLParam>) =>
+    update(rows.map((param, j) => i === j ? {...param, ...patch} : param).filter(param => param.name !== "" || param.value !== "" || param.type !== database.SQLParamType.STRING));
+  return m("div", rows.map((param, i) => m(NInputGroup, {key: i}, [
+    m(NInput, {
+      placeholder: "Name, empty for positional",
+      value: param.name,
+      on: {update: (name: string) => set(i, {name})},
+    }),
+    m(NSelect, {
+      value: param.type || database.SQLParamType.STRING,
+      options: paramTypeOptions,
+      on: {update: (type: string) => set(i, {type: type as database.SQLParamType})},
+    }),
+    m(NInput, {
+      placeholder: "Value, ${NAME} is environment variable",
+      value: param.value,
+      on: {update: (value: string) => set(i, {value})},
+    }),
+  ])));
+}
+
 export default function(
   id: string,
   show_request: () => boolean,
 ): m.Component<any, any> {
+  let schema: SQLNamespace | undefined = undefined;
+  const loadSchema = (refresh: boolean) => api.sqlSchema(id, refresh).then(res => {
+    if (res.kind === "err") {
+      console.error("Error fetching SQL schema", res.value);
+      return;
+    }
+    schema = schemaNamespace(res.value);
+    m.redraw();
+  });
+  let plan: app.SQLExplainResponse | null = null;
+  const explain = () => api.sqlExplain(id, true).then(res => {
+    if (res.kind === "err") {
+      console.error("Error explaining query", res.value);
+      return;
+    }
+    plan = res.value;
+    m.redraw();
+  });
+  let txOpen = false;
+  const loadTransaction = () => api.sqlTransactions().then(res => {
+    if (res.kind === "err") {
+      console.error("Error fetching transactions", res.value);
+      return;
+    }
+    txOpen = res.value.some(tx => tx.request_id === id);
+    m.redraw();
+  });
+  const transaction = (action: "begin" | "commit" | "rollback") => api.sqlTransaction(id, action).then(res => {
+    if (res.kind === "err") {
+      console.error("Error with transaction", action, res.value);
+    }
+    return loadTransaction();
+  });
   return {
+    oninit() {
+      loadSchema(false);
+      loadTransaction();
+    },
     view() {
       // {request, response, is_loading, send} =
       const r = use_request<Request, database.SQLResponse>(id);
@@ -167,7 +247,7 @@ export default function(
             style: {
               "grid-column": "span 2",
               display: "grid",
-              "grid-template-columns": "1fr 10fr 1fr",
+              "grid-template-columns": "1fr 1fr 6fr 1fr 1fr 1fr 1fr",
             },
           }, [
             m(NSelect, {
@@ -176,6 +256,11 @@ export default function(
               on: {update: (database: string) => update_request({database: database as Database})},
               // style: {width: "10%"},
             }),
+            m(NSelect, {
+              value: r.request.mode || database.SQLMode.AUTO,
+              options: Object.values(database.SQLMode).map(mode => ({label: mode.toUpperCase(), value: mode})),
+              on: {update: (mode: string) => update_request({mode: mode as database.SQLMode})},
+            }),
             m(NInput, {
               placeholder: "DSN",
               value: r.request.dsn,
@@ -183,32 +268,77 @@ export default function(
             }),
             m(NButton, {
               type: "primary",
-              on: {click: () => r.send()},
+              on: {click: () => {
+                plan = null;
+                r.send();
+              }},
               disabled: r.is_loading,
             }, "Run"),
+            m(NButton, {
+              on: {click: explain},
+              disabled: r.is_loading,
+            }, "Explain"),
+            txOpen ?
+            m("div", {style: {display: "flex"}}, [
+              m(NButton, {
+                type: "primary",
+                title: "Transaction is open",
+                on: {click: () => transaction("commit")},
+              }, "Commit"),
+              m(NButton, {
+                on: {click: () => transaction("rollback")},
+              }, "Rollback"),
+            ]) :
+            m(NButton, {
+              on: {click: () => transaction("begin")},
+            }, "Begin"),
+            m(NButton, {
+              on: {click: () => loadSchema(true)},
+            }, "Schema"),
           ]),
           m(NSplit, {}, [
-            show_request() && m(EditorSQL, {
-              value: r.request.query,
-              on: {update: (query: string) => update_request({query})},
+            show_request() && m("div", {
               class: "h100",
-            }),
+              style: {display: "grid", "grid-template-rows": "1fr auto", overflow: "hidden"},
+            }, [
+              m(EditorSQL, {
+                value: r.request.query,
+                schema: schema,
+                on: {update: (query: string) => update_request({query})},
+                class: "h100",
+              }),
+              viewParams(r.request.params ?? [], (params: database.SQLParam[]) => update_request({params})),
+            ]),
+            plan !== null ?
+            m(ViewJSON, {value: JSON.stringify(plan.plan, null, 2)}) :
             r.response === null ?
             m(NEmpty, {
               description: "Run query or choose one from history.",
               class: "h100",
               style: {"justify-content": "center"},
             }) :
-            // m(NScrollbar,
-              m(DataTable, {
-                columns: columns,
-                data: data,
-                "single-line": false,
-                size: "small",
-                resizable: true,
-                "scroll-x": r.response.columns.length * 200,
-              })
-            // ),
+            m("div", {class: "h100", style: {display: "flex", "flex-direction": "column"}}, [
+              (r.response.results ?? []).map(result => m("div", {
+                style: {color: result.error ? "red" : "grey", "white-space": "pre"},
+              }, [
+                result.error ? `ERROR: ${result.error}` :
+                result.columns ? `${(result.rows ?? []).length} rows` :
+                `${result.rows_affected ?? "?"} rows affected` +
+                  (result.last_insert_id ? `, last insert id ${result.last_insert_id}` : ""),
+                ` in ${(result.duration / 1e6).toFixed(1)}ms`,
+              ])),
+              r.response.columns &&
+              // m(NScrollbar,
+                m(DataTable, {
+                  columns: columns,
+                  data: data,
+                  "single-line": false,
+                  size: "small",
+                  resizable: true,
+                  "scroll-x": r.response.columns.length * 200,
+                })
+              // ),
+            ]),
           ]),
         ],
       );
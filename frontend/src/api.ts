@@ -163,4 +163,110 @@ export const api = {
   async grpcMethods(target: string): Promise<Result<app.grpcServiceMethods[]>> {
     return await wrap(() => App.GRPCMethods(target));
   },
+
+  async grpcProbe(
+    reqId: string,
+    services: string[] = [],
+    watchMillis: number = 0,
+  ): Promise<Result<app.GRPCProbeResponse>> {
+    return await wrap(() => App.GRPCProbe(reqId, services, watchMillis));
+  },
+
+  async grpcSchema(reqId: string, method: string): Promise<Result<string>> {
+    return await wrap(() => App.GRPCQuerySchema(reqId, method));
+  },
+
+  async grpcValidate(
+    reqId: string,
+    method: string,
+    payload: string,
+  ): Promise<Result<app.grpcPayloadError[]>> {
+    return await wrap(() => App.GRPCQueryValidate(reqId, method, payload));
+  },
+
+  async grpcStreamSend(reqId: string, payload: string): Promise<Result<void>> {
+    return await wrap(() => App.GRPCStreamSend(reqId, payload));
+  },
+
+  async grpcStreamClose(reqId: string): Promise<Result<void>> {
+    return await wrap(() => App.GRPCStreamClose(reqId));
+  },
+
+  async redisScan(
+    reqId: string,
+    pattern: string,
+    cursor: string = "0",
+    count: number = 100,
+  ): Promise<Result<app.RedisScanPage>> {
+    return await wrap(() => App.RedisScan(reqId, pattern, cursor, count));
+  },
+
+  async redisKeyValue(
+    reqId: string,
+    key: app.redisKeyInfo,
+    cursor: string = "0",
+    count: number = 100,
+  ): Promise<Result<app.RedisKeyPage>> {
+    return await wrap(() => App.RedisKeyValue(reqId, key.key, key.encoding, cursor, count));
+  },
+
+  async redisSubscriptionStop(reqId: string): Promise<Result<void>> {
+    return await wrap(() => App.RedisSubscriptionStop(reqId));
+  },
+
+  async historyDiff(
+    reqId: string,
+    a: number,
+    b: number,
+    keyColumns: string[] = [],
+  ): Promise<Result<app.HistoryDiffResponse>> {
+    return await wrap(() => App.DiffHistory(reqId, a, b, keyColumns));
+  },
+
+  async sqlSchema(
+    reqId: string,
+    refresh: boolean = false,
+  ): Promise<Result<app.SQLSchemaResponse>> {
+    return await wrap(() => refresh ? App.SQLSchemaRefresh(reqId) : App.SQLSchema(reqId));
+  },
+
+  async sqlExplain(
+    reqId: string,
+    analyze: boolean,
+  ): Promise<Result<app.SQLExplainResponse>> {
+    return await wrap(() => App.SQLExplain(reqId, analyze));
+  },
+
+  async sqlExport(
+    reqId: string,
+    historyIndex: number,
+    format: database.SQLExportFormat,
+    path: string,
+  ): Promise<Result<number>> {
+    return await wrap(() => App.ExportSQLResult(reqId, historyIndex, format, path));
+  },
+
+  async sqlImport(
+    reqId: string,
+    opts: app.SQLImportOptions,
+  ): Promise<Result<app.SQLImportResponse>> {
+    return await wrap(() => App.ImportSQLTable(reqId, opts));
+  },
+
+  async sqlTransactions(): Promise<Result<app.sqlTransaction[]>> {
+    return await wrap(() => App.SQLTransactions());
+  },
+
+  async sqlTransaction(
+    reqId: string,
+    action: "begin" | "commit" | "rollback",
+  ): Promise<Result<void>> {
+    return await wrap(() => {
+      switch (action) {
+      case "begin":    return App.SQLBegin(reqId);
+      case "commit":   return App.SQLCommit(reqId);
+      case "rollback": return App.SQLRollback(reqId);
+      }
+    });
+  },
 };
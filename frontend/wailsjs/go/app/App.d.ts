@@ -7,16 +7,30 @@ export function Create(arg1:string,arg2:database.Kind):Promise<app.ResponseNewRe
 
 export function Delete(arg1:string):Promise<void>;
 
+export function DiffHistory(arg1:string,arg2:number,arg3:number,arg4:Array<string>):Promise<app.HistoryDiffResponse>;
+
 export function Duplicate(arg1:string):Promise<void>;
 
+export function ExportSQLResult(arg1:string,arg2:number,arg3:database.SQLExportFormat,arg4:string):Promise<number>;
+
 export function GRPCMethods(arg1:string):Promise<Array<app.grpcServiceMethods>>;
 
+export function GRPCProbe(arg1:string,arg2:Array<string>,arg3:number):Promise<app.GRPCProbeResponse>;
+
 export function GRPCQueryFake(arg1:string,arg2:string):Promise<string>;
 
-export function GRPCQueryValidate(arg1:string,arg2:string,arg3:string):Promise<void>;
+export function GRPCQuerySchema(arg1:string,arg2:string):Promise<string>;
+
+export function GRPCQueryValidate(arg1:string,arg2:string,arg3:string):Promise<Array<app.grpcPayloadError>>;
+
+export function GRPCStreamClose(arg1:string):Promise<void>;
+
+export function GRPCStreamSend(arg1:string,arg2:string):Promise<void>;
 
 export function Get(arg1:string):Promise<app.GetResponse>;
 
+export function ImportSQLTable(arg1:string,arg2:app.SQLImportOptions):Promise<app.SQLImportResponse>;
+
 export function JQ(arg1:string,arg2:string):Promise<Array<string>>;
 
 export function List():Promise<app.ListResponse>;
@@ -25,6 +39,26 @@ export function Perform(arg1:string):Promise<Record<string, any>>;
 
 export function Read(arg1:string):Promise<database.Request>;
 
+export function RedisKeyValue(arg1:string,arg2:string,arg3:string,arg4:string,arg5:number):Promise<app.RedisKeyPage>;
+
+export function RedisScan(arg1:string,arg2:string,arg3:string,arg4:number):Promise<app.RedisScanPage>;
+
+export function RedisSubscriptionStop(arg1:string):Promise<void>;
+
 export function Rename(arg1:string,arg2:string):Promise<void>;
 
+export function SQLBegin(arg1:string):Promise<void>;
+
+export function SQLCommit(arg1:string):Promise<void>;
+
+export function SQLExplain(arg1:string,arg2:boolean):Promise<app.SQLExplainResponse>;
+
+export function SQLRollback(arg1:string):Promise<void>;
+
+export function SQLSchema(arg1:string):Promise<app.SQLSchemaResponse>;
+
+export function SQLSchemaRefresh(arg1:string):Promise<app.SQLSchemaResponse>;
+
+export function SQLTransactions():Promise<Array<app.sqlTransaction>>;
+
 export function Update(arg1:string,arg2:database.Kind,arg3:Record<string, any>):Promise<void>;
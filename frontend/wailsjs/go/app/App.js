@@ -10,26 +10,54 @@ export function Delete(arg1) {
   return window['go']['app']['App']['Delete'](arg1);
 }
 
+export function DiffHistory(arg1, arg2, arg3, arg4) {
+  return window['go']['app']['App']['DiffHistory'](arg1, arg2, arg3, arg4);
+}
+
 export function Duplicate(arg1) {
   return window['go']['app']['App']['Duplicate'](arg1);
 }
 
+export function ExportSQLResult(arg1, arg2, arg3, arg4) {
+  return window['go']['app']['App']['ExportSQLResult'](arg1, arg2, arg3, arg4);
+}
+
 export function GRPCMethods(arg1) {
   return window['go']['app']['App']['GRPCMethods'](arg1);
 }
 
+export function GRPCProbe(arg1, arg2, arg3) {
+  return window['go']['app']['App']['GRPCProbe'](arg1, arg2, arg3);
+}
+
 export function GRPCQueryFake(arg1, arg2) {
   return window['go']['app']['App']['GRPCQueryFake'](arg1, arg2);
 }
 
+export function GRPCQuerySchema(arg1, arg2) {
+  return window['go']['app']['App']['GRPCQuerySchema'](arg1, arg2);
+}
+
 export function GRPCQueryValidate(arg1, arg2, arg3) {
   return window['go']['app']['App']['GRPCQueryValidate'](arg1, arg2, arg3);
 }
 
+export function GRPCStreamClose(arg1) {
+  return window['go']['app']['App']['GRPCStreamClose'](arg1);
+}
+
+export function GRPCStreamSend(arg1, arg2) {
+  return window['go']['app']['App']['GRPCStreamSend'](arg1, arg2);
+}
+
 export function Get(arg1) {
   return window['go']['app']['App']['Get'](arg1);
 }
 
+export function ImportSQLTable(arg1, arg2) {
+  return window['go']['app']['App']['ImportSQLTable'](arg1, arg2);
+}
+
 export function JQ(arg1, arg2) {
   return window['go']['app']['App']['JQ'](arg1, arg2);
 }
@@ -46,10 +74,50 @@ export function Read(arg1) {
   return window['go']['app']['App']['Read'](arg1);
 }
 
+export function RedisKeyValue(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['app']['App']['RedisKeyValue'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function RedisScan(arg1, arg2, arg3, arg4) {
+  return window['go']['app']['App']['RedisScan'](arg1, arg2, arg3, arg4);
+}
+
+export function RedisSubscriptionStop(arg1) {
+  return window['go']['app']['App']['RedisSubscriptionStop'](arg1);
+}
+
 export function Rename(arg1, arg2) {
   return window['go']['app']['App']['Rename'](arg1, arg2);
 }
 
+export function SQLBegin(arg1) {
+  return window['go']['app']['App']['SQLBegin'](arg1);
+}
+
+export function SQLCommit(arg1) {
+  return window['go']['app']['App']['SQLCommit'](arg1);
+}
+
+export function SQLExplain(arg1, arg2) {
+  return window['go']['app']['App']['SQLExplain'](arg1, arg2);
+}
+
+export function SQLRollback(arg1) {
+  return window['go']['app']['App']['SQLRollback'](arg1);
+}
+
+export function SQLSchema(arg1) {
+  return window['go']['app']['App']['SQLSchema'](arg1);
+}
+
+export function SQLSchemaRefresh(arg1) {
+  return window['go']['app']['App']['SQLSchemaRefresh'](arg1);
+}
+
+export function SQLTransactions() {
+  return window['go']['app']['App']['SQLTransactions']();
+}
+
 export function Update(arg1, arg2, arg3) {
   return window['go']['app']['App']['Update'](arg1, arg2, arg3);
 }
@@ -123,6 +123,547 @@ export namespace app {
 	    }
 	}
 	
+	export class grpcHealthUpdate {
+	    status: string;
+	    // Go type: time
+	    received_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new grpcHealthUpdate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.received_at = this.convertValues(source["received_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class grpcServiceHealth {
+	    service: string;
+	    status: string;
+	    error: string;
+	    updates: grpcHealthUpdate[];
+	
+	    static createFrom(source: any = {}) {
+	        return new grpcServiceHealth(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.service = source["service"];
+	        this.status = source["status"];
+	        this.error = source["error"];
+	        this.updates = this.convertValues(source["updates"], grpcHealthUpdate);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GRPCProbeResponse {
+	    connect_latency: number;
+	    reflection: boolean;
+	    reflection_error: string;
+	    server_version: string;
+	    services: grpcServiceHealth[];
+	
+	    static createFrom(source: any = {}) {
+	        return new GRPCProbeResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connect_latency = source["connect_latency"];
+	        this.reflection = source["reflection"];
+	        this.reflection_error = source["reflection_error"];
+	        this.server_version = source["server_version"];
+	        this.services = this.convertValues(source["services"], grpcServiceHealth);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class historyChange {
+	    kind: string;
+	    path: string;
+	    old: any;
+	    new: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new historyChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.path = source["path"];
+	        this.old = source["old"];
+	        this.new = source["new"];
+	    }
+	}
+	export class HistoryDiffResponse {
+	    changes: historyChange[];
+	
+	    static createFrom(source: any = {}) {
+	        return new HistoryDiffResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.changes = this.convertValues(source["changes"], historyChange);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SQLImportColumn {
+	    name: string;
+	    target: string;
+	    type: database.SQLParamType;
+	
+	    static createFrom(source: any = {}) {
+	        return new SQLImportColumn(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.target = source["target"];
+	        this.type = source["type"];
+	    }
+	}
+	export class SQLImportOptions {
+	    path: string;
+	    table: string;
+	    create: boolean;
+	    columns: SQLImportColumn[];
+	    empty_strings: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SQLImportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.table = source["table"];
+	        this.create = source["create"];
+	        this.columns = this.convertValues(source["columns"], SQLImportColumn);
+	        this.empty_strings = source["empty_strings"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class sqlRejectedLine {
+	    line: number;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new sqlRejectedLine(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.line = source["line"];
+	        this.error = source["error"];
+	    }
+	}
+	export class SQLImportResponse {
+	    columns: SQLImportColumn[];
+	    loaded: number;
+	    rejected: sqlRejectedLine[];
+	    rejected_count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SQLImportResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.columns = this.convertValues(source["columns"], SQLImportColumn);
+	        this.loaded = source["loaded"];
+	        this.rejected = this.convertValues(source["rejected"], sqlRejectedLine);
+	        this.rejected_count = source["rejected_count"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class sqlPlanNode {
+	    type: string;
+	    detail: string;
+	    cost?: number;
+	    rows?: number;
+	    actual_rows?: number;
+	    time?: number;
+	    extra: Record<string, any>;
+	    children: sqlPlanNode[];
+	
+	    static createFrom(source: any = {}) {
+	        return new sqlPlanNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.detail = source["detail"];
+	        this.cost = source["cost"];
+	        this.rows = source["rows"];
+	        this.actual_rows = source["actual_rows"];
+	        this.time = source["time"];
+	        this.extra = source["extra"];
+	        this.children = this.convertValues(source["children"], sqlPlanNode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SQLExplainResponse {
+	    query: string;
+	    plan: sqlPlanNode;
+	    raw: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SQLExplainResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.query = source["query"];
+	        this.plan = this.convertValues(source["plan"], sqlPlanNode);
+	        this.raw = source["raw"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class sqlColumn {
+	    name: string;
+	    type: string;
+	    nullable: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new sqlColumn(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.nullable = source["nullable"];
+	    }
+	}
+	export class sqlForeignKey {
+	    name: string;
+	    columns: string[];
+	    ref_schema: string;
+	    ref_table: string;
+	    ref_columns: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new sqlForeignKey(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.columns = source["columns"];
+	        this.ref_schema = source["ref_schema"];
+	        this.ref_table = source["ref_table"];
+	        this.ref_columns = source["ref_columns"];
+	    }
+	}
+	export class sqlIndex {
+	    name: string;
+	    columns: string[];
+	    unique: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new sqlIndex(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.columns = source["columns"];
+	        this.unique = source["unique"];
+	    }
+	}
+	export class sqlTable {
+	    name: string;
+	    is_view: boolean;
+	    columns: sqlColumn[];
+	    indexes: sqlIndex[];
+	    foreign_keys: sqlForeignKey[];
+	
+	    static createFrom(source: any = {}) {
+	        return new sqlTable(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.is_view = source["is_view"];
+	        this.columns = this.convertValues(source["columns"], sqlColumn);
+	        this.indexes = this.convertValues(source["indexes"], sqlIndex);
+	        this.foreign_keys = this.convertValues(source["foreign_keys"], sqlForeignKey);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class sqlSchema {
+	    name: string;
+	    tables: sqlTable[];
+	
+	    static createFrom(source: any = {}) {
+	        return new sqlSchema(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.tables = this.convertValues(source["tables"], sqlTable);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SQLSchemaResponse {
+	    schemas: sqlSchema[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SQLSchemaResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.schemas = this.convertValues(source["schemas"], sqlSchema);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class sqlTransaction {
+	    request_id: string;
+	    database: database.Database;
+	    // Go type: time
+	    started_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new sqlTransaction(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.request_id = source["request_id"];
+	        this.database = source["database"];
+	        this.started_at = this.convertValues(source["started_at"], null);
+	    }	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class grpcPayloadError {
+	    path: string;
+	    message: string;
+	    from: number;
+	    to: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new grpcPayloadError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.message = source["message"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	    }
+	}
 	export class grpcServiceMethods {
 	    service: string;
 	    methods: string[];
@@ -137,6 +678,166 @@ export namespace app {
 	        this.methods = source["methods"];
 	    }
 	}
+	export class respEntry {
+	    key: respValue;
+	    value: respValue;
+	
+	    static createFrom(source: any = {}) {
+	        return new respEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = this.convertValues(source["key"], respValue);
+	        this.value = this.convertValues(source["value"], respValue);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class respValue {
+	    type: string;
+	    value?: any;
+	    encoding?: string;
+	    format?: string;
+	    items?: respValue[];
+	    entries?: respEntry[];
+	
+	    static createFrom(source: any = {}) {
+	        return new respValue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.value = source["value"];
+	        this.encoding = source["encoding"];
+	        this.format = source["format"];
+	        this.items = this.convertValues(source["items"], respValue);
+	        this.entries = this.convertValues(source["entries"], respEntry);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RedisKeyPage {
+	    type: string;
+	    total: number;
+	    cursor: string;
+	    value: respValue;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisKeyPage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.total = source["total"];
+	        this.cursor = source["cursor"];
+	        this.value = this.convertValues(source["value"], respValue);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class redisKeyInfo {
+	    key: string;
+	    encoding: string;
+	    type: string;
+	    ttl: number;
+	    memory_usage: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new redisKeyInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.encoding = source["encoding"];
+	        this.type = source["type"];
+	        this.ttl = source["ttl"];
+	        this.memory_usage = source["memory_usage"];
+	    }
+	}
+	export class RedisScanPage {
+	    cursor: string;
+	    keys: redisKeyInfo[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisScanPage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.cursor = source["cursor"];
+	        this.keys = this.convertValues(source["keys"], redisKeyInfo);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
 
@@ -162,6 +863,64 @@ export namespace database {
 	    TIME = "time",
 	    BOOLEAN = "boolean",
 	}
+	export enum SQLMode {
+	    AUTO = "auto",
+	    QUERY = "query",
+	    EXEC = "exec",
+	}
+	export enum SQLParamType {
+	    STRING = "string",
+	    INT = "int",
+	    FLOAT = "float",
+	    BOOL = "bool",
+	    TIME = "time",
+	    NULL = "null",
+	}
+	export enum SQLExportFormat {
+	    CSV = "csv",
+	    TSV = "tsv",
+	    JSONL = "jsonl",
+	    MARKDOWN = "markdown",
+	    INSERT = "insert",
+	}
+	export enum KnownHostsPolicy {
+	    STRICT = "strict",
+	    ACCEPT_NEW = "accept_new",
+	    INSECURE = "insecure",
+	}
+	export enum GRPCProtocol {
+	    GRPC = "grpc",
+	    GRPC_WEB = "grpc_web",
+	    GRPC_WEB_TEXT = "grpc_web_text",
+	    CONNECT_JSON = "connect_json",
+	    CONNECT_PROTO = "connect_proto",
+	}
+	export enum RedisMode {
+	    QUERY = "query",
+	    SUBSCRIBE = "subscribe",
+	    PSUBSCRIBE = "psubscribe",
+	    XREAD = "xread",
+	    XREADGROUP = "xreadgroup",
+	}
+	export enum RedisDeploymentKind {
+	    STANDALONE = "standalone",
+	    SENTINEL = "sentinel",
+	    CLUSTER = "cluster",
+	}
+	export enum JQInputFormat {
+	    JSON = "json",
+	    YAML = "yaml",
+	    TOML = "toml",
+	    CSV = "csv",
+	    XML = "xml",
+	}
+	export enum JQOutputFormat {
+	    JSON = "json",
+	    COMPACT_JSON = "compact_json",
+	    RAW = "raw",
+	    YAML = "yaml",
+	    CSV = "csv",
+	}
 	export class KV {
 	    key: string;
 	    value: string;
@@ -176,11 +935,106 @@ export namespace database {
 	        this.value = source["value"];
 	    }
 	}
+	export class SSHTunnel {
+	    host: string;
+	    user: string;
+	    key_file: string;
+	    known_hosts: KnownHostsPolicy;
+	
+	    static createFrom(source: any = {}) {
+	        return new SSHTunnel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.user = source["user"];
+	        this.key_file = source["key_file"];
+	        this.known_hosts = source["known_hosts"];
+	    }
+	}
+	export class GRPCMessage {
+	    data: string;
+	    // Go type: time
+	    received_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new GRPCMessage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.data = source["data"];
+	        this.received_at = this.convertValues(source["received_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GRPCProtos {
+	    import_paths: string[];
+	    files: string[];
+	    protosets: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new GRPCProtos(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.import_paths = source["import_paths"];
+	        this.files = source["files"];
+	        this.protosets = source["protosets"];
+	    }
+	}
+	export class GRPCTLS {
+	    ca_file: string;
+	    cert_file: string;
+	    key_file: string;
+	    server_name: string;
+	    insecure: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new GRPCTLS(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ca_file = source["ca_file"];
+	        this.cert_file = source["cert_file"];
+	        this.key_file = source["key_file"];
+	        this.server_name = source["server_name"];
+	        this.insecure = source["insecure"];
+	    }
+	}
 	export class GRPCRequest {
 	    target: string;
 	    method: string;
 	    payload: string;
 	    metadata: KV[];
+	    ssh?: SSHTunnel;
+	    protocol: GRPCProtocol;
+	    protos?: GRPCProtos;
+	    tls?: GRPCTLS;
+	    authority: string;
+	    max_recv_size: number;
+	    max_send_size: number;
+	    deadline: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new GRPCRequest(source);
@@ -192,6 +1046,14 @@ export namespace database {
 	        this.method = source["method"];
 	        this.payload = source["payload"];
 	        this.metadata = this.convertValues(source["metadata"], KV);
+	        this.ssh = this.convertValues(source["ssh"], SSHTunnel);
+	        this.protocol = source["protocol"];
+	        this.protos = this.convertValues(source["protos"], GRPCProtos);
+	        this.tls = this.convertValues(source["tls"], GRPCTLS);
+	        this.authority = source["authority"];
+	        this.max_recv_size = source["max_recv_size"];
+	        this.max_send_size = source["max_send_size"];
+	        this.deadline = source["deadline"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -215,7 +1077,11 @@ export namespace database {
 	export class GRPCResponse {
 	    response: string;
 	    code: number;
+	    status: string;
 	    metadata: KV[];
+	    trailers: KV[];
+	    details: string[];
+	    messages: GRPCMessage[];
 	
 	    static createFrom(source: any = {}) {
 	        return new GRPCResponse(source);
@@ -225,7 +1091,11 @@ export namespace database {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.response = source["response"];
 	        this.code = source["code"];
+	        this.status = source["status"];
 	        this.metadata = this.convertValues(source["metadata"], KV);
+	        this.trailers = this.convertValues(source["trailers"], KV);
+	        this.details = source["details"];
+	        this.messages = this.convertValues(source["messages"], GRPCMessage);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -354,9 +1224,30 @@ export namespace database {
 		    return a;
 		}
 	}
+	export class JQArg {
+	    name: string;
+	    value: string;
+	    json: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new JQArg(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.value = source["value"];
+	        this.json = source["json"];
+	    }
+	}
 	export class JQRequest {
 	    query: string;
 	    json: string;
+	    input: string;
+	    input_index: number;
+	    args: JQArg[];
+	    input_format: JQInputFormat;
+	    output_format: JQOutputFormat;
 	
 	    static createFrom(source: any = {}) {
 	        return new JQRequest(source);
@@ -366,10 +1257,34 @@ export namespace database {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.query = source["query"];
 	        this.json = source["json"];
+	        this.input = source["input"];
+	        this.input_index = source["input_index"];
+	        this.args = this.convertValues(source["args"], JQArg);
+	        this.input_format = source["input_format"];
+	        this.output_format = source["output_format"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class JQResponse {
 	    response: string[];
+	    format: JQOutputFormat;
 	
 	    static createFrom(source: any = {}) {
 	        return new JQResponse(source);
@@ -378,6 +1293,7 @@ export namespace database {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.response = source["response"];
+	        this.format = source["format"];
 	    }
 	}
 	
@@ -405,9 +1321,48 @@ export namespace database {
 	        this.data = source["data"];
 	    }
 	}
+	export class RedisSubscription {
+	    channels: string[];
+	    group: string;
+	    consumer: string;
+	    start: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisSubscription(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.channels = source["channels"];
+	        this.group = source["group"];
+	        this.consumer = source["consumer"];
+	        this.start = source["start"];
+	    }
+	}
+	export class RedisDeployment {
+	    kind: RedisDeploymentKind;
+	    master_name: string;
+	    nodes: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisDeployment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.master_name = source["master_name"];
+	        this.nodes = source["nodes"];
+	    }
+	}
 	export class RedisRequest {
 	    dsn: string;
 	    query: string;
+	    ssh?: SSHTunnel;
+	    transaction: boolean;
+	    mode: RedisMode;
+	    subscription: RedisSubscription;
+	    deployment: RedisDeployment;
 	
 	    static createFrom(source: any = {}) {
 	        return new RedisRequest(source);
@@ -417,10 +1372,98 @@ export namespace database {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.dsn = source["dsn"];
 	        this.query = source["query"];
+	        this.ssh = this.convertValues(source["ssh"], SSHTunnel);
+	        this.transaction = source["transaction"];
+	        this.mode = source["mode"];
+	        this.subscription = this.convertValues(source["subscription"], RedisSubscription);
+	        this.deployment = this.convertValues(source["deployment"], RedisDeployment);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RedisResult {
+	    command: string;
+	    response: string;
+	    value: string;
+	    error: string;
+	    latency: number;
+	    node: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.command = source["command"];
+	        this.response = source["response"];
+	        this.value = source["value"];
+	        this.error = source["error"];
+	        this.latency = source["latency"];
+	        this.node = source["node"];
+	    }
+	}
+	export class RedisMessage {
+	    channel: string;
+	    pattern: string;
+	    id: string;
+	    payload: string;
+	    // Go type: time
+	    received_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedisMessage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.channel = source["channel"];
+	        this.pattern = source["pattern"];
+	        this.id = source["id"];
+	        this.payload = source["payload"];
+	        this.received_at = this.convertValues(source["received_at"], null);
 	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class RedisResponse {
 	    response: string;
+	    protocol: number;
+	    results: RedisResult[];
+	    messages: RedisMessage[];
+	    error: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new RedisResponse(source);
@@ -429,7 +1472,29 @@ export namespace database {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.response = source["response"];
+	        this.protocol = source["protocol"];
+	        this.results = this.convertValues(source["results"], RedisResult);
+	        this.messages = this.convertValues(source["messages"], RedisMessage);
+	        this.error = source["error"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class Request {
 	    ID: string;
@@ -465,10 +1530,29 @@ export namespace database {
 		    return a;
 		}
 	}
+	export class SQLParam {
+	    name: string;
+	    type: SQLParamType;
+	    value: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SQLParam(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.value = source["value"];
+	    }
+	}
 	export class SQLRequest {
 	    dsn: string;
 	    database: Database;
 	    query: string;
+	    mode: SQLMode;
+	    params: SQLParam[];
+	    ssh?: SSHTunnel;
 	
 	    static createFrom(source: any = {}) {
 	        return new SQLRequest(source);
@@ -479,12 +1563,60 @@ export namespace database {
 	        this.dsn = source["dsn"];
 	        this.database = source["database"];
 	        this.query = source["query"];
+	        this.mode = source["mode"];
+	        this.params = this.convertValues(source["params"], SQLParam);
+	        this.ssh = this.convertValues(source["ssh"], SSHTunnel);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SQLResult {
+	    query: string;
+	    columns: string[];
+	    types: string[];
+	    rows: any[][];
+	    rows_affected?: number;
+	    last_insert_id?: number;
+	    duration: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SQLResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.query = source["query"];
+	        this.columns = source["columns"];
+	        this.types = source["types"];
+	        this.rows = source["rows"];
+	        this.rows_affected = source["rows_affected"];
+	        this.last_insert_id = source["last_insert_id"];
+	        this.duration = source["duration"];
+	        this.error = source["error"];
 	    }
 	}
 	export class SQLResponse {
 	    columns: string[];
 	    types: string[];
 	    rows: any[][];
+	    results: SQLResult[];
 	
 	    static createFrom(source: any = {}) {
 	        return new SQLResponse(source);
@@ -495,7 +1627,26 @@ export namespace database {
 	        this.columns = source["columns"];
 	        this.types = source["types"];
 	        this.rows = source["rows"];
+	        this.results = this.convertValues(source["results"], SQLResult);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
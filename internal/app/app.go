@@ -2,21 +2,74 @@ package app
 
 import (
 	"context"
+	"sync"
 
+	"github.com/fullstorydev/grpcurl"
 	"github.com/spf13/afero"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/rprtr258/impulse/internal/database"
 )
 
+// _jqModulesDir is directory of workspace with modules available to jq queries by import and include
+const _jqModulesDir = ".jq"
+
 type App struct {
 	ctx context.Context
 	DB  *database.DB
+
+	sqlSchemasMu sync.Mutex
+	sqlSchemas   map[string]SQLSchemaResponse // NOTE: by database and DSN
+
+	sqlSessionsMu sync.Mutex
+	sqlSessions   map[database.RequestID]*sqlSession
+
+	sshTunnelsMu sync.Mutex
+	sshTunnels   map[string]*sshTunnel // NOTE: by ssh config and remote address
+	sshDials     singleflight.Group
+
+	grpcSourcesMu sync.Mutex
+	grpcSources   map[string]grpcurl.DescriptorSource // NOTE: by target or proto files
+
+	grpcStreamsMu sync.Mutex
+	grpcStreams   map[database.RequestID]*grpcStream // NOTE: open bidirectional streams
+
+	redisConnsMu sync.Mutex
+	redisConns   map[string]*redisCachedConn // NOTE: by node address and connection options
+
+	redisTopologiesMu sync.Mutex
+	redisTopologies   map[string]*redisTopology // NOTE: by ssh tunnel, dsn and deployment
+
+	redisSubscriptionsMu sync.Mutex
+	redisSubscriptions   map[database.RequestID]*redisSubscription
+
+	jqModules afero.Fs // NOTE: library of jq modules in workspace
 }
 
 func New(dbFs afero.Fs) (*App, func(context.Context), func()) {
 	db := database.New(dbFs)
-	s := &App{DB: db}
+	s := &App{
+		DB:          db,
+		sqlSchemas:  map[string]SQLSchemaResponse{},
+		sqlSessions: map[database.RequestID]*sqlSession{},
+		sshTunnels:  map[string]*sshTunnel{},
+		grpcSources: map[string]grpcurl.DescriptorSource{},
+		grpcStreams: map[database.RequestID]*grpcStream{},
+
+		redisConns:         map[string]*redisCachedConn{},
+		redisTopologies:    map[string]*redisTopology{},
+		redisSubscriptions: map[database.RequestID]*redisSubscription{},
+
+		jqModules: afero.NewBasePathFs(dbFs, _jqModulesDir),
+	}
 	return s,
 		func(ctx context.Context) { s.ctx = ctx },
-		func() { db.Close() }
+		func() {
+			s.rollbackSQLSessions()
+			s.stopRedisSubscriptions()
+			s.closeRedisTopologies()
+			s.closeRedisConns()
+			s.closeSSHTunnels()
+			db.Close()
+		}
 }
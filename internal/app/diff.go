@@ -0,0 +1,321 @@
+package app
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+type changeKind string
+
+const (
+	changeAdded   changeKind = "added"
+	changeRemoved changeKind = "removed"
+	changeChanged changeKind = "changed"
+)
+
+type historyChange struct {
+	Kind changeKind `json:"kind"`
+	// Path is location of change like `body.items[0].name`, `headers.Content-Type` or `rows[id=1].name`
+	Path string `json:"path"`
+	Old  any    `json:"old"`
+	New  any    `json:"new"`
+}
+
+type HistoryDiffResponse struct {
+	Changes []historyChange `json:"changes"`
+}
+
+type differ struct {
+	changes []historyChange
+}
+
+func (d *differ) add(kind changeKind, path string, old, new any) {
+	d.changes = append(d.changes, historyChange{kind, path, old, new})
+}
+
+func (d *differ) value(path string, old, new any) {
+	if !reflect.DeepEqual(old, new) {
+		d.add(changeChanged, path, old, new)
+	}
+}
+
+var _reIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func jsonPathKey(path, key string) string {
+	if !_reIdentifier.MatchString(key) {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// json compares decoded json values recursively
+func (d *differ) json(path string, old, new any) {
+	switch old := old.(type) {
+	case map[string]any:
+		new, ok := new.(map[string]any)
+		if !ok {
+			d.add(changeChanged, path, old, new)
+			return
+		}
+
+		keys := make([]string, 0, len(old)+len(new))
+		for k := range old {
+			keys = append(keys, k)
+		}
+		for k := range new {
+			if _, ok := old[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			oldV, inOld := old[k]
+			newV, inNew := new[k]
+			switch {
+			case !inNew:
+				d.add(changeRemoved, jsonPathKey(path, k), oldV, nil)
+			case !inOld:
+				d.add(changeAdded, jsonPathKey(path, k), nil, newV)
+			default:
+				d.json(jsonPathKey(path, k), oldV, newV)
+			}
+		}
+	case []any:
+		new, ok := new.([]any)
+		if !ok {
+			d.add(changeChanged, path, old, new)
+			return
+		}
+
+		for i := range max(len(old), len(new)) {
+			itemPath := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(new):
+				d.add(changeRemoved, itemPath, old[i], nil)
+			case i >= len(old):
+				d.add(changeAdded, itemPath, nil, new[i])
+			default:
+				d.json(itemPath, old[i], new[i])
+			}
+		}
+	default:
+		d.value(path, old, new)
+	}
+}
+
+// text compares bodies as json if both are valid json, otherwise as strings
+func (d *differ) text(path, old, new string) {
+	var oldV, newV any
+	if json.Unmarshal([]byte(old), &oldV) == nil && json.Unmarshal([]byte(new), &newV) == nil {
+		d.json(path, oldV, newV)
+		return
+	}
+	d.value(path, old, new)
+}
+
+// kvs compares headers or metadata, values of repeated keys are compared as lists
+func (d *differ) kvs(path string, old, new []database.KV) {
+	group := func(kvs []database.KV) (map[string][]string, []string) {
+		m := map[string][]string{}
+		var keys []string
+		for _, kv := range kvs {
+			if _, ok := m[kv.Key]; !ok {
+				keys = append(keys, kv.Key)
+			}
+			m[kv.Key] = append(m[kv.Key], kv.Value)
+		}
+		return m, keys
+	}
+	value := func(vs []string) any {
+		if len(vs) == 1 {
+			return vs[0]
+		}
+		return vs
+	}
+
+	oldM, oldKeys := group(old)
+	newM, newKeys := group(new)
+	for _, k := range oldKeys {
+		if vs, ok := newM[k]; !ok {
+			d.add(changeRemoved, path+"."+k, value(oldM[k]), nil)
+		} else if !slices.Equal(oldM[k], vs) {
+			d.add(changeChanged, path+"."+k, value(oldM[k]), value(vs))
+		}
+	}
+	for _, k := range newKeys {
+		if _, ok := oldM[k]; !ok {
+			d.add(changeAdded, path+"."+k, nil, value(newM[k]))
+		}
+	}
+}
+
+func rowObject(columns []string, row []any) map[string]any {
+	obj := make(map[string]any, len(columns))
+	for i, column := range columns {
+		if i < len(row) {
+			obj[column] = row[i]
+		}
+	}
+	return obj
+}
+
+// sqlRowKeys returns key of every row, rows are identified by position if no key columns given
+func sqlRowKeys(columns []string, rows [][]any, keyColumns []string) ([]string, error) {
+	indexes := make([]int, len(keyColumns))
+	for i, column := range keyColumns {
+		if indexes[i] = slices.Index(columns, column); indexes[i] == -1 {
+			return nil, errors.Errorf("key column %q not found", column)
+		}
+	}
+
+	keys := make([]string, len(rows))
+	seen := map[string]int{}
+	for i, row := range rows {
+		if len(keyColumns) == 0 {
+			keys[i] = strconv.Itoa(i)
+			continue
+		}
+
+		parts := make([]string, len(keyColumns))
+		for j, column := range keyColumns {
+			b, _ := json.Marshal(row[indexes[j]])
+			parts[j] = column + "=" + string(b)
+		}
+		key := strings.Join(parts, ",")
+		// NOTE: rows with duplicate keys are matched in order of appearance
+		if seen[key]++; seen[key] > 1 {
+			key += "#" + strconv.Itoa(seen[key])
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+func (d *differ) sql(old, new database.SQLResponse, keyColumns []string) error {
+	for _, column := range old.Columns {
+		if !slices.Contains(new.Columns, column) {
+			d.add(changeRemoved, jsonPathKey("columns", column), column, nil)
+		}
+	}
+	for _, column := range new.Columns {
+		if !slices.Contains(old.Columns, column) {
+			d.add(changeAdded, jsonPathKey("columns", column), nil, column)
+		}
+	}
+
+	oldKeys, err := sqlRowKeys(old.Columns, old.Rows, keyColumns)
+	if err != nil {
+		return errors.Wrap(err, "old entry")
+	}
+	newKeys, err := sqlRowKeys(new.Columns, new.Rows, keyColumns)
+	if err != nil {
+		return errors.Wrap(err, "new entry")
+	}
+
+	newRows := make(map[string]map[string]any, len(newKeys))
+	for i, key := range newKeys {
+		newRows[key] = rowObject(new.Columns, new.Rows[i])
+	}
+	oldRows := make(map[string]struct{}, len(oldKeys))
+	for i, key := range oldKeys {
+		oldRows[key] = struct{}{}
+		path := "rows[" + key + "]"
+		oldRow := rowObject(old.Columns, old.Rows[i])
+		newRow, ok := newRows[key]
+		if !ok {
+			d.add(changeRemoved, path, oldRow, nil)
+			continue
+		}
+
+		for _, column := range old.Columns {
+			if newV, ok := newRow[column]; ok {
+				d.value(jsonPathKey(path, column), oldRow[column], newV)
+			}
+		}
+	}
+	for i, key := range newKeys {
+		if _, ok := oldRows[key]; !ok {
+			d.add(changeAdded, "rows["+key+"]", nil, rowObject(new.Columns, new.Rows[i]))
+		}
+	}
+	return nil
+}
+
+// jsonItems decodes every item as json, invalid items are kept as strings
+func jsonItems(items []string) []any {
+	res := make([]any, len(items))
+	for i, item := range items {
+		if json.Unmarshal([]byte(item), &res[i]) != nil {
+			res[i] = item
+		}
+	}
+	return res
+}
+
+func (d *differ) responses(old, new database.ResponseData, keyColumns []string) error {
+	switch old := old.(type) {
+	case database.HTTPResponse:
+		new := new.(database.HTTPResponse)
+		d.value("code", old.Code, new.Code)
+		d.kvs("headers", old.Headers, new.Headers)
+		d.text("body", old.Body, new.Body)
+	case database.GRPCResponse:
+		new := new.(database.GRPCResponse)
+		d.value("code", old.Code, new.Code)
+		d.kvs("metadata", old.Metadata, new.Metadata)
+		d.kvs("trailers", old.Trailers, new.Trailers)
+		d.text("response", old.Response, new.Response)
+		d.json("details", jsonItems(old.Details), jsonItems(new.Details))
+	case database.JQResponse:
+		new := new.(database.JQResponse)
+		d.json("response", jsonItems(old.Response), jsonItems(new.Response))
+	case database.RedisResponse:
+		d.text("response", old.Response, new.(database.RedisResponse).Response)
+	case database.MarkdownResponse:
+		d.value("data", old.Data, new.(database.MarkdownResponse).Data)
+	case database.SQLResponse:
+		return d.sql(old, new.(database.SQLResponse), keyColumns)
+	default:
+		return errors.Errorf("unsupported response %T", old)
+	}
+	return nil
+}
+
+// DiffHistory compares responses of two history entries of request, indexes count back from the latest entry, 0 being the latest.
+// SQL rows are matched by keyColumns values, or by position if no key columns given.
+func (a *App) DiffHistory(requestID string, i, j int, keyColumns []string) (HistoryDiffResponse, error) {
+	request, err := database.Get(a.ctx, a.DB, database.RequestID(requestID))
+	if err != nil {
+		return HistoryDiffResponse{}, errors.Wrapf(err, "get request id=%q", requestID)
+	}
+
+	old, err := historyAt(request, i)
+	if err != nil {
+		return HistoryDiffResponse{}, err
+	}
+	new, err := historyAt(request, j)
+	if err != nil {
+		return HistoryDiffResponse{}, err
+	}
+	if reflect.TypeOf(old.Response) != reflect.TypeOf(new.Response) {
+		return HistoryDiffResponse{}, errors.Errorf("can't compare %T and %T responses", old.Response, new.Response)
+	}
+
+	d := differ{changes: []historyChange{}}
+	if err := d.responses(old.Response, new.Response, keyColumns); err != nil {
+		return HistoryDiffResponse{}, err
+	}
+	return HistoryDiffResponse{d.changes}, nil
+}
@@ -1,205 +1,84 @@
 package app
 
 import (
-	"bytes"
-	"context"
-	"encoding/json"
 	"fmt"
-	"math/rand"
 	"strings"
+	"time"
 
 	"github.com/fullstorydev/grpcurl"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/jhump/protoreflect/desc"
-	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/pkg/errors"
 	"github.com/rprtr258/fun"
 	"github.com/rprtr258/fun/exp/zun"
-	"google.golang.org/grpc"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/reflect/protoreflect"
-	"google.golang.org/protobuf/types/dynamicpb"
 
 	"github.com/rprtr258/impulse/internal/database"
 )
 
-func connect(
-	ctx context.Context,
-	target string,
-) (grpcurl.DescriptorSource, *grpc.ClientConn, error) {
-	cc, err := grpcurl.BlockingDial(ctx, "tcp", target, nil)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "dial")
-	}
-
-	refClient := grpcreflect.NewClientAuto(ctx, cc)
-	refClient.AllowMissingFileDescriptors()
-	return grpcurl.DescriptorSourceFromServer(ctx, refClient), cc, nil
-}
-
 func splitService(serviceName string) (pkg, short string) {
 	dotI := strings.LastIndexByte(serviceName, '.')
 	return serviceName[:dotI], serviceName[dotI+1:]
 }
 
-// JSONSchema represents the structure of a JSON schema
-type JSONSchema struct {
-	Type string `json:"type"`
-	// type == "object"
-	Properties map[string]JSONSchema `json:"properties,omitempty"`
-	// type == "object" && "oneOf" is present
-	OneOf []JSONSchema `json:"oneOf,omitempty"`
-	// type == "array"
-	Items *JSONSchema `json:"items,omitempty"`
+type grpcServiceMethods struct {
+	Service string   `json:"service"`
+	Methods []string `json:"methods"`
 }
 
-func convertObjectToJSONSchema(msg protoreflect.MessageDescriptor) (JSONSchema, error) {
-	fields := msg.Fields()
-	properties := make(map[string]JSONSchema, fields.Len())
-	for i := 0; i < fields.Len(); i++ {
-		field := fields.Get(i)
-		fieldSchema, err := convertFieldToJSONSchema(field)
-		if err != nil {
-			return JSONSchema{}, err
-		}
-
-		if oneof := field.ContainingOneof(); oneof != nil {
-			oneofName := string(oneof.Name())
-			if _, ok := properties[oneofName]; !ok {
-				properties[oneofName] = JSONSchema{
-					Type:  "object",
-					OneOf: []JSONSchema{},
-				}
-			}
-			m := properties[oneofName]
-			m.OneOf = append(m.OneOf, JSONSchema{
-				Type:       "object",
-				Properties: map[string]JSONSchema{string(field.Name()): fieldSchema},
-			})
-			properties[oneofName] = m
-		} else {
-			properties[string(field.Name())] = fieldSchema
-		}
+func (a *App) getGRPCRequest(id string) (database.GRPCRequest, error) {
+	request, err := database.Get(a.ctx, a.DB, database.RequestID(id))
+	if err != nil {
+		return database.GRPCRequest{}, errors.Wrapf(err, "get request id=%q", id)
 	}
 
-	return JSONSchema{
-		Type:       "object",
-		Properties: properties,
-		Items:      nil,
-	}, nil
+	req, ok := request.Data.(database.GRPCRequest)
+	if !ok {
+		return database.GRPCRequest{}, errors.Errorf("query kind is %s, expected grpc", request.Data.Kind())
+	}
+	return req, nil
 }
 
-func convertFieldToJSONSchema(field protoreflect.FieldDescriptor) (JSONSchema, error) {
-	var fieldSchema JSONSchema
-	switch field.Kind() {
-	case protoreflect.BoolKind:
-		fieldSchema.Type = "boolean"
-	case protoreflect.Int32Kind, protoreflect.Int64Kind,
-		protoreflect.Uint32Kind, protoreflect.Uint64Kind,
-		protoreflect.Sint32Kind, protoreflect.Sint64Kind,
-		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind,
-		protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
-		fieldSchema.Type = "integer"
-	case protoreflect.FloatKind,
-		protoreflect.DoubleKind:
-		fieldSchema.Type = "number"
-	case protoreflect.StringKind:
-		fieldSchema.Type = "string"
-	case protoreflect.BytesKind:
-		fieldSchema.Type = "string"
-		// TODO: support bytes
-	case protoreflect.MessageKind:
-		var err error
-		fieldSchema, err = convertObjectToJSONSchema(field.Message())
-		if err != nil {
-			return JSONSchema{}, err
-		}
-	case protoreflect.EnumKind:
-		fieldSchema.Type = "string"
-		vals := field.Enum().Values()
-		for i := 0; i < vals.Len(); i++ {
-			val := vals.Get(i)
-			fmt.Println("ENUM", i, val.Name())
-		}
-	default:
-		return JSONSchema{}, fmt.Errorf("unsupported field kind: %v", field.Kind())
+// grpcInputType returns descriptor of input message of request method
+func (a *App) grpcInputType(requestID, method string) (protoreflect.MessageDescriptor, error) {
+	req, err := a.getGRPCRequest(requestID)
+	if err != nil {
+		return nil, err
 	}
 
-	if field.IsList() {
-		return JSONSchema{
-			Type:  "array",
-			Items: &fieldSchema,
-		}, nil
+	reflSource, err := a.grpcSource(req, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "get descriptors")
 	}
 
-	return fieldSchema, nil
-}
-
-func ConvertMessageToJSONSchema(msg protoreflect.Message) (JSONSchema, error) {
-	return convertObjectToJSONSchema(msg.Descriptor())
-}
-
-func newFake(js JSONSchema) any {
-	switch js.Type {
-	case "object":
-		m := make(map[string]any, len(js.Properties))
-		for k, v := range js.Properties {
-			if v.Type == "object" && v.OneOf != nil {
-				idx := rand.Intn(len(v.OneOf))
-
-				// TODO: (((GO)))VNO
-				var kkk string
-				var vvv JSONSchema
-				for k, v := range v.OneOf[idx].Properties {
-					kkk = k
-					vvv = v
-				}
-
-				m[kkk] = newFake(vvv)
-			} else {
-				m[k] = newFake(v)
-			}
-		}
-		return m
-	case "array":
-		a := []any{}
-		for _, v := range js.Items.Properties {
-			a = append(a, newFake(v))
-		}
-		return a
-	case "number":
-		return float64(5.2)
-	case "integer":
-		return int64(52)
-	case "string":
-		return "ABOBA"
-	default:
-		return nil
+	dsc, err := reflSource.FindSymbol(method)
+	if err != nil {
+		return nil, errors.Wrap(err, "find method")
 	}
-}
 
-type grpcServiceMethods struct {
-	Service string   `json:"service"`
-	Methods []string `json:"methods"`
+	methodDesc, ok := dsc.(*desc.MethodDescriptor)
+	if !ok {
+		return nil, errors.Errorf("%s is not a method", method)
+	}
+	return methodDesc.GetInputType().UnwrapMessage(), nil
 }
 
 func (a *App) GRPCMethods(id string) ([]grpcServiceMethods, error) {
-	request, err := database.Get(a.ctx, a.DB, database.RequestID(id))
+	req, err := a.getGRPCRequest(id)
 	if err != nil {
-		return nil, errors.Wrapf(err, "get request id=%q", id)
-	}
-	if kind := request.Data.Kind(); kind != database.KindGRPC {
-		return nil, errors.Errorf("query kind is %s, expected grpc", kind)
+		return nil, err
 	}
 
-	reflSource, cc, err := connect(a.ctx, request.Data.(database.GRPCRequest).Target)
+	// NOTE: listing methods is explicit user action, so descriptors are always reloaded
+	reflSource, err := a.grpcSource(req, true)
 	if err != nil {
-		return nil, errors.Wrap(err, "connect")
+		return nil, errors.Wrap(err, "get descriptors")
 	}
-	defer cc.Close()
 
 	services, err := grpcurl.ListServices(reflSource)
 	if err != nil {
@@ -226,42 +105,6 @@ func (a *App) GRPCMethods(id string) ([]grpcServiceMethods, error) {
 	return res, nil
 }
 
-func (a *App) GRPCQueryFake(
-	Target string,
-	Method string, // NOTE: fully qualified
-) (string, error) {
-	reflSource, cc, err := connect(a.ctx, Target)
-	if err != nil {
-		return "", errors.Wrap(err, "connect")
-	}
-	defer cc.Close()
-
-	dsc, err := reflSource.FindSymbol(Method)
-	if err != nil {
-		return "", errors.Wrap(err, "find method")
-	}
-
-	methodDesc := dsc.(*desc.MethodDescriptor)
-	// fmt.Println("    IN", strings.TrimPrefix(inputType.GetFullyQualifiedName(), pkg+"."))
-	// fmt.Println("    OUT", strings.TrimPrefix(methodDesc.GetOutputType().GetFullyQualifiedName(), pkg+"."))
-	m := dynamicpb.NewMessage(methodDesc.GetInputType().UnwrapMessage())
-
-	schema, err := ConvertMessageToJSONSchema(m)
-	// schema["$schema"]= "http://json-schema.org/schema#"
-	if err != nil {
-		return "", errors.Wrap(err, "convert message to json schema")
-	}
-
-	// jsonSchema, err := json.MarshalIndent(schema, "", "  ")
-	// check(err)
-
-	b, err := json.MarshalIndent(newFake(schema), "", "  ")
-	if err != nil {
-		return "", errors.Wrap(err, "marshal fake")
-	}
-	return string(b), nil
-}
-
 type invocationHandler struct {
 	onResolveMethod   func(*desc.MethodDescriptor)
 	onSendHeaders     func(metadata.MD)
@@ -302,8 +145,19 @@ func (h *invocationHandler) OnReceiveTrailers(stat *status.Status, md metadata.M
 	}
 }
 
-func (a *App) sendGRPC(req database.GRPCRequest) (database.GRPCResponse, error) {
-	reflSource, cc, err := connect(a.ctx, req.Target)
+func (a *App) sendGRPC(id database.RequestID, req database.GRPCRequest) (database.GRPCResponse, error) {
+	reflSource, err := a.grpcSource(req, false)
+	if err != nil {
+		return database.GRPCResponse{}, errors.Wrap(err, "get descriptors")
+	}
+	if !grpcIsNative(req) {
+		return a.sendGRPCHTTP(id, req, reflSource)
+	}
+
+	ctx, cancel := a.grpcContext(req)
+	defer cancel()
+
+	cc, err := a.dialGRPC(ctx, req)
 	if err != nil {
 		return database.GRPCResponse{}, errors.Wrap(err, "connect")
 	}
@@ -315,63 +169,96 @@ func (a *App) sendGRPC(req database.GRPCRequest) (database.GRPCResponse, error)
 		headers = append(headers, fmt.Sprintf("%s: %s", kv.Key, kv.Value))
 	}
 
-	var body bytes.Buffer
+	stream := newGRPCStream(req.Payload)
+	defer stream.finish()
+	defer func() {
+		a.grpcStreamsMu.Lock()
+		defer a.grpcStreamsMu.Unlock()
+		if a.grpcStreams[id] == stream {
+			delete(a.grpcStreams, id)
+		}
+	}()
+
+	var messages []database.GRPCMessage
 	var st status.Status
-	meta := metadata.MD{}
-	r := bytes.NewReader([]byte(req.Payload))
+	var respHeaders, trailers metadata.MD
 	if err := grpcurl.InvokeRPC(
-		a.ctx, reflSource, cc, req.Method,
+		ctx, reflSource, cc, req.Method,
 		headers,
 		&invocationHandler{
+			onResolveMethod: func(md *desc.MethodDescriptor) {
+				stream.bidi = md.IsClientStreaming() && md.IsServerStreaming()
+				if stream.bidi {
+					a.grpcStreamsMu.Lock()
+					a.grpcStreams[id] = stream
+					a.grpcStreamsMu.Unlock()
+				}
+			},
 			onReceiveResponse: func(m proto.Message) {
-				_ = (&jsonpb.Marshaler{}).Marshal(&body, m)
+				data, _ := (&jsonpb.Marshaler{}).MarshalToString(m)
+				messages = append(messages, a.grpcMessage(id, data))
 			},
 			onReceiveHeaders: func(md metadata.MD) {
-				for k, vs := range md {
-					meta[k] = append(meta[k], vs...)
-				}
+				respHeaders = md
 			},
 			onReceiveTrailers: func(stat *status.Status, md metadata.MD) {
+				stream.finish()
 				st = fun.Deref(stat)
-				for k, vs := range md {
-					meta[k] = append(meta[k], vs...)
-				}
+				trailers = md.Copy()
+				delete(trailers, "grpc-status-details-bin") // NOTE: decoded into details
 			},
 		},
-		grpcurl.NewJSONRequestParserWithUnmarshaler(r, jsonpb.Unmarshaler{}).Next,
+		stream.next,
 	); err != nil {
 		return database.GRPCResponse{}, errors.Wrap(err, "invoke rpc")
 	}
 
-	kvs := make([]database.KV, 0, len(meta))
-	for k, vs := range meta {
-		for _, v := range vs {
-			kvs = append(kvs, database.KV{
-				Key:   k,
-				Value: v,
-			})
-		}
-	}
+	return grpcResponse(reflSource, &st, respHeaders, trailers, messages), nil
+}
 
+// grpcMessage records received message and notifies frontend about it
+func (a *App) grpcMessage(id database.RequestID, data string) database.GRPCMessage {
+	message := database.GRPCMessage{data, time.Now()}
+	runtime.EventsEmit(a.ctx, "grpc:message:"+string(id), message)
+	return message
+}
+
+func grpcResponse(
+	source grpcurl.DescriptorSource,
+	st *status.Status,
+	headers, trailers metadata.MD,
+	messages []database.GRPCMessage,
+) database.GRPCResponse {
 	if code := st.Code(); code != codes.OK {
 		return database.GRPCResponse{
-			st.Message(),
-			int(code),
-			kvs,
-		}, nil
+			Response: st.Message(),
+			Code:     int(code),
+			Status:   grpcCodeName(code),
+			Metadata: metadataKVs(headers),
+			Trailers: metadataKVs(trailers),
+			Details:  grpcStatusDetails(source, st),
+			Messages: messages,
+		}
 	}
 
-	return database.GRPCResponse{
-		body.String(),
-		int(codes.OK),
-		kvs,
-	}, nil
-}
+	var body string
+	if len(messages) == 1 {
+		body = messages[0].Data
+	} else {
+		// NOTE: stream responses are shown as json array
+		items := make([]string, len(messages))
+		for i, message := range messages {
+			items[i] = message.Data
+		}
+		body = "[" + strings.Join(items, ",") + "]"
+	}
 
-func (a *App) GRPCQueryValidate(
-	Target string,
-	Method string, // NOTE: fully qualified
-	Payload string,
-) error {
-	return errors.New("not implemented")
+	return database.GRPCResponse{
+		Response: body,
+		Code:     int(codes.OK),
+		Status:   grpcCodeName(codes.OK),
+		Metadata: metadataKVs(headers),
+		Trailers: metadataKVs(trailers),
+		Messages: messages,
+	}
 }
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+// _grpcProbeTimeout limits connect, reflection and health checks of probe if request has no deadline
+const _grpcProbeTimeout = 5 * time.Second
+
+type grpcHealthUpdate struct {
+	Status     string    `json:"status"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+type grpcServiceHealth struct {
+	// Service is empty for overall server health
+	Service string `json:"service"`
+	Status  string `json:"status"`
+	Error   string `json:"error"`
+	// Updates are statuses received while watching
+	Updates []grpcHealthUpdate `json:"updates"`
+}
+
+type GRPCProbeResponse struct {
+	ConnectLatency  time.Duration `json:"connect_latency"`
+	Reflection      bool          `json:"reflection"`
+	ReflectionError string        `json:"reflection_error"`
+	// ServerVersion is value of "server" header or trailer of health checks, empty if server does not send it
+	ServerVersion string              `json:"server_version"`
+	Services      []grpcServiceHealth `json:"services"`
+}
+
+// grpcHealthCheck checks health of service, also returns "server" header or trailer of reply
+func grpcHealthCheck(ctx context.Context, client healthpb.HealthClient, service string) (grpcServiceHealth, string) {
+	var header, trailer metadata.MD
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service}, grpc.Header(&header), grpc.Trailer(&trailer))
+
+	var version string
+	if vs := append(header.Get("server"), trailer.Get("server")...); len(vs) > 0 {
+		version = vs[0]
+	}
+	if err != nil {
+		return grpcServiceHealth{Service: service, Error: err.Error()}, version
+	}
+	return grpcServiceHealth{Service: service, Status: resp.GetStatus().String()}, version
+}
+
+// grpcHealthWatch collects status updates of service until ctx is done
+func grpcHealthWatch(ctx context.Context, client healthpb.HealthClient, health *grpcServiceHealth) {
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: health.Service})
+	if err != nil {
+		health.Error = err.Error()
+		return
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() == nil {
+				health.Error = err.Error()
+			}
+			return
+		}
+
+		status := resp.GetStatus().String()
+		health.Status = status
+		health.Updates = append(health.Updates, grpcHealthUpdate{status, time.Now()})
+	}
+}
+
+// GRPCProbe checks whether request target is alive: measures connect latency, checks reflection availability
+// and health of given services, or of all services listed by reflection if none given.
+// If watchMillis is positive, health updates are watched for that long.
+func (a *App) GRPCProbe(requestID string, services []string, watchMillis int) (GRPCProbeResponse, error) {
+	req, err := a.getGRPCRequest(requestID)
+	if err != nil {
+		return GRPCProbeResponse{}, err
+	}
+	if !grpcIsNative(req) {
+		return GRPCProbeResponse{}, errors.Errorf("probe is not available over %s", req.Protocol)
+	}
+
+	if req.Deadline <= 0 {
+		req.Deadline = _grpcProbeTimeout
+	}
+	ctx, cancel := a.grpcContext(req)
+	defer cancel()
+
+	start := time.Now()
+	cc, err := a.dialGRPC(ctx, req)
+	if err != nil {
+		return GRPCProbeResponse{}, errors.Wrap(err, "connect")
+	}
+	defer cc.Close()
+
+	res := GRPCProbeResponse{ConnectLatency: time.Since(start)}
+
+	refClient := grpcreflect.NewClientAuto(ctx, cc)
+	defer refClient.Reset()
+	listed, err := refClient.ListServices()
+	if err != nil {
+		res.ReflectionError = err.Error()
+	} else {
+		res.Reflection = true
+	}
+
+	if len(services) == 0 {
+		// NOTE: empty service is overall server health
+		services = append([]string{""}, slices.DeleteFunc(listed, func(service string) bool {
+			switch service {
+			case healthpb.Health_ServiceDesc.ServiceName,
+				"grpc.reflection.v1.ServerReflection",
+				"grpc.reflection.v1alpha.ServerReflection":
+				return true
+			}
+			return false
+		})...)
+	}
+
+	client := healthpb.NewHealthClient(cc)
+	res.Services = make([]grpcServiceHealth, len(services))
+	for i, service := range services {
+		var version string
+		res.Services[i], version = grpcHealthCheck(ctx, client, service)
+		if res.ServerVersion == "" {
+			res.ServerVersion = version
+		}
+	}
+
+	if watchMillis > 0 {
+		// NOTE: watching is limited by its own duration only, not by probe deadline
+		watchCtx, cancel := context.WithTimeout(a.ctx, time.Duration(watchMillis)*time.Millisecond)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for i := range res.Services {
+			if res.Services[i].Error != "" {
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				grpcHealthWatch(watchCtx, client, &res.Services[i])
+			}()
+		}
+		wg.Wait()
+	}
+
+	return res, nil
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/spf13/afero"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// startTestHealthServer serves health service, headers are sent with every reply
+func startTestHealthServer(t *testing.T, headers metadata.MD) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := grpc.SetHeader(ctx, headers); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}))
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("app.Service", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func TestGRPCProbeServerVersion(t *testing.T) {
+	a, start, stop := New(afero.NewMemMapFs())
+	start(context.Background())
+	defer stop()
+
+	for _, tc := range []struct {
+		name    string
+		headers metadata.MD
+		want    string
+	}{
+		{"server header", metadata.Pairs("server", "envoy"), "envoy"},
+		{"no server header", metadata.MD{}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			target := startTestHealthServer(t, tc.headers)
+			if _, err := a.Create(tc.name, database.KindGRPC); err != nil {
+				t.Fatal(err)
+			}
+			if err := a.Update(tc.name, database.KindGRPC, map[string]any{"target": target}); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := a.GRPCProbe(tc.name, []string{"", "app.Service"}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.ServerVersion != tc.want {
+				t.Fatalf("server version is %q, want %q", res.ServerVersion, tc.want)
+			}
+			want := []string{"SERVING", "NOT_SERVING"}
+			for i, service := range res.Services {
+				if service.Status != want[i] {
+					t.Fatalf("status of %q is %q (%s), want %q", service.Service, service.Status, service.Error, want[i])
+				}
+			}
+		})
+	}
+}
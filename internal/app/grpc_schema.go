@@ -0,0 +1,394 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// JSONSchema is subset of JSON schema (draft 2020-12) describing messages in their json form
+type JSONSchema struct {
+	Schema      string   `json:"$schema,omitempty"`
+	Ref         string   `json:"$ref,omitempty"`
+	Type        string   `json:"type,omitempty"` // NOTE: empty for any value
+	Format      string   `json:"format,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	// type == "object"
+	Properties           map[string]JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *JSONSchema           `json:"additionalProperties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	// AllOf holds oneof constraints, each allowing at most one field of oneof to be set
+	AllOf []JSONSchema `json:"allOf,omitempty"`
+	OneOf []JSONSchema `json:"oneOf,omitempty"`
+	AnyOf []JSONSchema `json:"anyOf,omitempty"`
+	Not   *JSONSchema  `json:"not,omitempty"`
+	// type == "array"
+	Items *JSONSchema `json:"items,omitempty"`
+	// Defs holds schemas of all messages, referenced by full name, so recursive messages are supported
+	Defs map[string]JSONSchema `json:"$defs,omitempty"`
+}
+
+func protoComments(d protoreflect.Descriptor) string {
+	loc := d.ParentFile().SourceLocations().ByDescriptor(d)
+	if comments := strings.TrimSpace(loc.LeadingComments); comments != "" {
+		return comments
+	}
+	return strings.TrimSpace(loc.TrailingComments)
+}
+
+// wellKnownSchema returns schemas of well-known types json forms
+func wellKnownSchema(md protoreflect.MessageDescriptor) (JSONSchema, bool) {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return JSONSchema{Type: "string", Format: "date-time"}, true
+	case "google.protobuf.Duration":
+		return JSONSchema{Type: "string", Format: "duration", Description: `Duration in seconds with "s" suffix, like "1.5s"`}, true
+	case "google.protobuf.FieldMask":
+		return JSONSchema{Type: "string", Description: "Comma separated field paths"}, true
+	case "google.protobuf.Struct":
+		return JSONSchema{Type: "object"}, true
+	case "google.protobuf.ListValue":
+		return JSONSchema{Type: "array", Items: &JSONSchema{}}, true
+	case "google.protobuf.Value":
+		return JSONSchema{}, true
+	case "google.protobuf.Empty":
+		return JSONSchema{Type: "object", Properties: map[string]JSONSchema{}}, true
+	case "google.protobuf.Any":
+		return JSONSchema{
+			Type:       "object",
+			Properties: map[string]JSONSchema{"@type": {Type: "string"}},
+			Required:   []string{"@type"},
+		}, true
+	case "google.protobuf.BoolValue", "google.protobuf.BytesValue", "google.protobuf.StringValue",
+		"google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int32Value", "google.protobuf.Int64Value",
+		"google.protobuf.UInt32Value", "google.protobuf.UInt64Value":
+		return scalarSchema(md.Fields().ByName("value")), true
+	default:
+		return JSONSchema{}, false
+	}
+}
+
+func scalarSchema(fd protoreflect.FieldDescriptor) JSONSchema {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return JSONSchema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return JSONSchema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return JSONSchema{Type: "integer", Format: "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return JSONSchema{Type: "integer", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return JSONSchema{Type: "integer", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return JSONSchema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return JSONSchema{Type: "number", Format: "double"}
+	case protoreflect.StringKind:
+		return JSONSchema{Type: "string"}
+	case protoreflect.BytesKind:
+		return JSONSchema{Type: "string", Format: "byte", Description: "Base64 encoded bytes"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]string, values.Len())
+		for i := range values.Len() {
+			names[i] = string(values.Get(i).Name())
+		}
+		return JSONSchema{Type: "string", Enum: names, Description: protoComments(fd.Enum())}
+	default:
+		return JSONSchema{} // NOTE: messages are handled by jsonSchemaBuilder
+	}
+}
+
+type jsonSchemaBuilder struct {
+	defs map[string]JSONSchema
+}
+
+// message returns reference to message schema, building it once
+func (b *jsonSchemaBuilder) message(md protoreflect.MessageDescriptor) JSONSchema {
+	if schema, ok := wellKnownSchema(md); ok {
+		return schema
+	}
+
+	name := string(md.FullName())
+	ref := JSONSchema{Ref: "#/$defs/" + name}
+	if _, ok := b.defs[name]; ok {
+		return ref
+	}
+	b.defs[name] = JSONSchema{} // NOTE: placeholder guarding recursion
+
+	fields := md.Fields()
+	schema := JSONSchema{
+		Type:        "object",
+		Description: protoComments(md),
+		Properties:  make(map[string]JSONSchema, fields.Len()),
+	}
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		fieldSchema := b.field(fd)
+		if comments := protoComments(fd); comments != "" {
+			fieldSchema.Description = comments
+		}
+		schema.Properties[fd.JSONName()] = fieldSchema
+		if fd.Cardinality() == protoreflect.Required {
+			schema.Required = append(schema.Required, fd.JSONName())
+		}
+	}
+
+	oneofs := md.Oneofs()
+	for i := range oneofs.Len() {
+		oneof := oneofs.Get(i)
+		if oneof.IsSynthetic() {
+			continue // NOTE: proto3 optional field
+		}
+
+		oneofFields := oneof.Fields()
+		options := make([]JSONSchema, oneofFields.Len())
+		for j := range oneofFields.Len() {
+			options[j] = JSONSchema{Required: []string{oneofFields.Get(j).JSONName()}}
+		}
+		schema.AllOf = append(schema.AllOf, JSONSchema{
+			Description: "At most one field of oneof " + string(oneof.Name()) + " can be set",
+			OneOf:       append(options, JSONSchema{Not: &JSONSchema{AnyOf: options}}),
+		})
+	}
+
+	b.defs[name] = schema
+	return ref
+}
+
+func (b *jsonSchemaBuilder) single(fd protoreflect.FieldDescriptor) JSONSchema {
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return b.message(fd.Message())
+	}
+	return scalarSchema(fd)
+}
+
+func (b *jsonSchemaBuilder) field(fd protoreflect.FieldDescriptor) JSONSchema {
+	switch {
+	case fd.IsMap():
+		value := b.single(fd.MapValue())
+		return JSONSchema{Type: "object", AdditionalProperties: &value}
+	case fd.IsList():
+		item := b.single(fd)
+		return JSONSchema{Type: "array", Items: &item}
+	default:
+		return b.single(fd)
+	}
+}
+
+// messageJSONSchema returns schema of message json form
+func messageJSONSchema(md protoreflect.MessageDescriptor) JSONSchema {
+	b := &jsonSchemaBuilder{defs: map[string]JSONSchema{}}
+	schema := b.message(md)
+	schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+	if len(b.defs) > 0 {
+		schema.Defs = b.defs
+	}
+	return schema
+}
+
+type orderedField struct {
+	key   string
+	value any
+}
+
+// orderedObject is json object keeping fields order, so that examples follow declaration order
+type orderedObject []orderedField
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "marshal field %s", field.key)
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// exampleString guesses realistic value by field name
+func exampleString(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "email"):
+		return "user@example.com"
+	case strings.Contains(lower, "url"), strings.Contains(lower, "uri"), strings.Contains(lower, "link"):
+		return "https://example.com"
+	case strings.Contains(lower, "uuid"):
+		return "123e4567-e89b-12d3-a456-426614174000"
+	case strings.Contains(lower, "phone"):
+		return "+15555550100"
+	case lower == "id", strings.HasSuffix(lower, "id"), strings.HasSuffix(lower, "ids"):
+		return "1"
+	default:
+		return name
+	}
+}
+
+// exampleBuilder generates deterministic example of message json form
+type exampleBuilder struct {
+	stack map[protoreflect.FullName]bool // NOTE: messages being generated, to stop recursion
+}
+
+func (b *exampleBuilder) wellKnown(md protoreflect.MessageDescriptor) (any, bool) {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return "2024-01-01T00:00:00Z", true
+	case "google.protobuf.Duration":
+		return "1s", true
+	case "google.protobuf.FieldMask":
+		return "", true
+	case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.Empty":
+		return orderedObject{}, true
+	case "google.protobuf.ListValue":
+		return []any{}, true
+	case "google.protobuf.Any":
+		return orderedObject{{"@type", "type.googleapis.com/google.protobuf.Empty"}}, true
+	}
+	if _, ok := _wellKnownJSON[md.FullName()]; ok { // NOTE: wrappers
+		return b.scalar(md.Fields().ByName("value"), "value"), true
+	}
+	return nil, false
+}
+
+func (b *exampleBuilder) message(md protoreflect.MessageDescriptor) (any, bool) {
+	if value, ok := b.wellKnown(md); ok {
+		return value, true
+	}
+	if b.stack[md.FullName()] {
+		return nil, false
+	}
+	b.stack[md.FullName()] = true
+	defer delete(b.stack, md.FullName())
+
+	fields := md.Fields()
+	obj := make(orderedObject, 0, fields.Len())
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		// NOTE: only first field of oneof is set
+		if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() && oneof.Fields().Get(0) != fd {
+			continue
+		}
+
+		if value, ok := b.field(fd); ok {
+			obj = append(obj, orderedField{fd.JSONName(), value})
+		}
+	}
+	return obj, true
+}
+
+func (b *exampleBuilder) scalar(fd protoreflect.FieldDescriptor, name string) any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return true
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return 1.5
+	case protoreflect.StringKind:
+		return exampleString(name)
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString([]byte(name))
+	case protoreflect.EnumKind:
+		// NOTE: zero value is usually UNSPECIFIED, so first meaningful value is preferred
+		values := fd.Enum().Values()
+		for i := range values.Len() {
+			if values.Get(i).Number() != 0 {
+				return string(values.Get(i).Name())
+			}
+		}
+		return string(values.Get(0).Name())
+	default:
+		return 1
+	}
+}
+
+func (b *exampleBuilder) single(fd protoreflect.FieldDescriptor, name string) (any, bool) {
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return b.message(fd.Message())
+	}
+	return b.scalar(fd, name), true
+}
+
+func (b *exampleBuilder) field(fd protoreflect.FieldDescriptor) (any, bool) {
+	switch {
+	case fd.IsMap():
+		obj := orderedObject{}
+		if value, ok := b.single(fd.MapValue(), fd.JSONName()); ok {
+			key := "key"
+			switch fd.MapKey().Kind() {
+			case protoreflect.StringKind:
+			case protoreflect.BoolKind:
+				key = "true"
+			default:
+				key = "1"
+			}
+			obj = append(obj, orderedField{key, value})
+		}
+		return obj, true
+	case fd.IsList():
+		items := []any{}
+		if item, ok := b.single(fd, fd.JSONName()); ok {
+			items = append(items, item)
+		}
+		return items, true
+	default:
+		return b.single(fd, fd.JSONName())
+	}
+}
+
+// messageExample returns example json of message, same for same descriptor
+func messageExample(md protoreflect.MessageDescriptor) (string, error) {
+	example, _ := (&exampleBuilder{stack: map[protoreflect.FullName]bool{}}).message(md)
+	b, err := json.MarshalIndent(example, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "marshal example")
+	}
+	return string(b), nil
+}
+
+// GRPCQueryFake returns example payload of request method
+func (a *App) GRPCQueryFake(
+	requestID string,
+	Method string, // NOTE: fully qualified
+) (string, error) {
+	md, err := a.grpcInputType(requestID, Method)
+	if err != nil {
+		return "", err
+	}
+	return messageExample(md)
+}
+
+// GRPCQuerySchema returns JSON schema of request method payload, used for validation in editor
+func (a *App) GRPCQuerySchema(
+	requestID string,
+	Method string, // NOTE: fully qualified
+) (string, error) {
+	md, err := a.grpcInputType(requestID, Method)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(messageJSONSchema(md), "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "marshal schema")
+	}
+	return string(b), nil
+}
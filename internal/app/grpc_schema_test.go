@@ -0,0 +1,107 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExampleString(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"email", "user@example.com"},
+		{"userEmail", "user@example.com"},
+		{"avatarUrl", "https://example.com"},
+		{"redirectURI", "https://example.com"},
+		{"uuid", "123e4567-e89b-12d3-a456-426614174000"},
+		{"phoneNumber", "+15555550100"},
+		{"id", "1"},
+		{"userId", "1"},
+		{"orderIds", "1"},
+		{"title", "title"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := exampleString(tc.name); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMessageExample(t *testing.T) {
+	md := testRequestDescriptor(t)
+
+	got, err := messageExample(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: fields follow declaration order, only first field of oneof is set and recursive child is skipped
+	want := `{
+  "count": 1,
+  "big": 1,
+  "small": 1,
+  "userEmail": "user@example.com",
+  "data": "ZGF0YQ==",
+  "ratio": 1.5,
+  "flag": true,
+  "status": "STATUS_OK",
+  "items": [
+    {
+      "name": "name"
+    }
+  ],
+  "labels": {
+    "1": "labels"
+  },
+  "at": "2024-01-01T00:00:00Z",
+  "a": "a"
+}`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if errs := validateGRPCPayload(md, got); len(errs) != 0 {
+		t.Errorf("example is invalid: %+v", errs)
+	}
+}
+
+func TestMessageJSONSchema(t *testing.T) {
+	schema := messageJSONSchema(testRequestDescriptor(t))
+	if schema.Ref != "#/$defs/test.Request" {
+		t.Fatalf("got ref %q", schema.Ref)
+	}
+	request := schema.Defs["test.Request"]
+
+	for _, tc := range []struct {
+		field string
+		want  JSONSchema
+	}{
+		{"count", JSONSchema{Type: "integer", Format: "int32"}},
+		{"big", JSONSchema{Type: "integer", Format: "int64"}},
+		{"small", JSONSchema{Type: "integer", Format: "uint32"}},
+		{"userEmail", JSONSchema{Type: "string"}},
+		{"data", JSONSchema{Type: "string", Format: "byte", Description: "Base64 encoded bytes"}},
+		{"ratio", JSONSchema{Type: "number", Format: "double"}},
+		{"flag", JSONSchema{Type: "boolean"}},
+		{"status", JSONSchema{Type: "string", Enum: []string{"STATUS_UNSPECIFIED", "STATUS_OK"}}},
+		{"items", JSONSchema{Type: "array", Items: &JSONSchema{Ref: "#/$defs/test.Item"}}},
+		{"labels", JSONSchema{Type: "object", AdditionalProperties: &JSONSchema{Type: "string"}}},
+		{"at", JSONSchema{Type: "string", Format: "date-time"}},
+		{"child", JSONSchema{Ref: "#/$defs/test.Request"}},
+	} {
+		t.Run(tc.field, func(t *testing.T) {
+			if got := request.Properties[tc.field]; !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	if _, ok := schema.Defs["test.Item"]; !ok {
+		t.Error("schema of test.Item is not defined")
+	}
+	if len(request.AllOf) != 1 || len(request.AllOf[0].OneOf) != 3 {
+		t.Errorf("got oneof constraints %+v, want single constraint with option for each field and none", request.AllOf)
+	}
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// grpcIsNative reports whether request uses native grpc protocol, old requests have no protocol set
+func grpcIsNative(req database.GRPCRequest) bool {
+	return req.Protocol == "" || req.Protocol == database.GRPCProtocolGRPC
+}
+
+// grpcTLSConfig returns tls config of request, host is address of target used to verify certificate when tunneling
+func grpcTLSConfig(req database.GRPCRequest, host string) (*tls.Config, error) {
+	cfg, err := grpcurl.ClientTLSConfig(req.TLS.Insecure, req.TLS.CAFile, req.TLS.CertFile, req.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ServerName = req.TLS.ServerName
+	if cfg.ServerName == "" && req.Authority == "" && req.SSH != nil {
+		// NOTE: verify certificate against real host, not tunnel address
+		cfg.ServerName = host
+	}
+	return cfg, nil
+}
+
+func grpcCredentials(req database.GRPCRequest) (credentials.TransportCredentials, error) {
+	if req.TLS == nil {
+		return nil, nil // NOTE: plaintext
+	}
+
+	host, _, _ := net.SplitHostPort(req.Target)
+	cfg, err := grpcTLSConfig(req, host)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+func (a *App) dialGRPC(ctx context.Context, req database.GRPCRequest) (*grpc.ClientConn, error) {
+	target, err := a.tunnel(req.SSH, req.Target)
+	if err != nil {
+		return nil, errors.Wrap(err, "open ssh tunnel")
+	}
+
+	creds, err := grpcCredentials(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "load tls config")
+	}
+
+	opts := []grpc.DialOption{}
+	if req.Authority != "" {
+		opts = append(opts, grpc.WithAuthority(req.Authority))
+	}
+	callOpts := []grpc.CallOption{}
+	if req.MaxRecvSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(req.MaxRecvSize))
+	}
+	if req.MaxSendSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(req.MaxSendSize))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+
+	cc, err := grpcurl.BlockingDial(ctx, "tcp", target, creds, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "dial")
+	}
+	return cc, nil
+}
+
+// grpcContext returns context limited by request deadline
+func (a *App) grpcContext(req database.GRPCRequest) (context.Context, context.CancelFunc) {
+	if req.Deadline <= 0 {
+		return context.WithCancel(a.ctx)
+	}
+	return context.WithTimeout(a.ctx, req.Deadline)
+}
+
+// filesKey identifies set of files along with their modification times, so that edited files are reloaded
+func filesKey(importPaths, files []string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.Join(importPaths, ":"))
+	for _, file := range files {
+		sb.WriteString(" " + file)
+		for _, dir := range append([]string{""}, importPaths...) {
+			if stat, err := os.Stat(filepath.Join(dir, file)); err == nil {
+				sb.WriteString("@" + strconv.FormatInt(stat.ModTime().UnixNano(), 10))
+				break
+			}
+		}
+	}
+	return sb.String()
+}
+
+func grpcSourceKey(req database.GRPCRequest) string {
+	switch {
+	case req.Protos == nil:
+		return "reflection " + req.Target
+	case len(req.Protos.Protosets) > 0:
+		return "protosets " + filesKey(nil, req.Protos.Protosets)
+	default:
+		return "files " + filesKey(req.Protos.ImportPaths, req.Protos.Files)
+	}
+}
+
+func (a *App) loadGRPCSource(req database.GRPCRequest) (grpcurl.DescriptorSource, error) {
+	switch {
+	case req.Protos == nil && !grpcIsNative(req):
+		return nil, errors.Errorf("server reflection is not available over %s, set proto files or protosets", req.Protocol)
+	case req.Protos == nil:
+		ctx, cancel := a.grpcContext(req)
+		defer cancel()
+
+		cc, err := a.dialGRPC(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		defer cc.Close()
+
+		refClient := grpcreflect.NewClientAuto(ctx, cc)
+		refClient.AllowMissingFileDescriptors()
+		defer refClient.Reset()
+
+		// NOTE: fetch all descriptors at once, so that source does not depend on connection
+		files, err := grpcurl.GetAllFiles(grpcurl.DescriptorSourceFromServer(ctx, refClient))
+		if err != nil {
+			return nil, errors.Wrap(err, "fetch descriptors using reflection")
+		}
+		return grpcurl.DescriptorSourceFromFileDescriptors(files...)
+	case len(req.Protos.Protosets) > 0 && len(req.Protos.Files) > 0:
+		return nil, errors.New("either proto files or protosets must be given, not both")
+	case len(req.Protos.Protosets) > 0:
+		return grpcurl.DescriptorSourceFromProtoSets(req.Protos.Protosets...)
+	case len(req.Protos.Files) > 0:
+		return grpcurl.DescriptorSourceFromProtoFiles(req.Protos.ImportPaths, req.Protos.Files...)
+	default:
+		return nil, errors.New("no proto files or protosets given")
+	}
+}
+
+// grpcSource returns descriptors of request services, loaded from proto files, protosets
+// or using server reflection. Descriptors are cached per target or file set, refresh reloads them.
+func (a *App) grpcSource(req database.GRPCRequest, refresh bool) (grpcurl.DescriptorSource, error) {
+	key := grpcSourceKey(req)
+	if !refresh {
+		a.grpcSourcesMu.Lock()
+		source, ok := a.grpcSources[key]
+		a.grpcSourcesMu.Unlock()
+		if ok {
+			return source, nil
+		}
+	}
+
+	source, err := a.loadGRPCSource(req)
+	if err != nil {
+		return nil, err
+	}
+
+	a.grpcSourcesMu.Lock()
+	a.grpcSources[key] = source
+	a.grpcSourcesMu.Unlock()
+	return source, nil
+}
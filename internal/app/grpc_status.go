@@ -0,0 +1,114 @@
+package app
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // NOTE: register BadRequest, ErrorInfo, RetryInfo, etc.
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// grpcTypeResolver resolves well-known and registered types, falling back to descriptor source for custom ones
+type grpcTypeResolver struct {
+	source grpcurl.DescriptorSource
+}
+
+func (r grpcTypeResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(name); err == nil {
+		return mt, nil
+	}
+
+	d, err := r.source.FindSymbol(string(name))
+	if err != nil {
+		return nil, protoregistry.NotFound
+	}
+	md, ok := d.(*desc.MessageDescriptor)
+	if !ok {
+		return nil, errors.Errorf("%s is not a message", name)
+	}
+	return dynamicpb.NewMessageType(md.UnwrapMessage()), nil
+}
+
+func (r grpcTypeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	name := url
+	if i := strings.LastIndexByte(url, '/'); i != -1 {
+		name = url[i+1:]
+	}
+	return r.FindMessageByName(protoreflect.FullName(name))
+}
+
+func (r grpcTypeResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+func (r grpcTypeResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
+
+// Resolve implements jsonpb.AnyResolver
+func (r grpcTypeResolver) Resolve(typeURL string) (protoadapt.MessageV1, error) {
+	mt, err := r.FindMessageByURL(typeURL)
+	if err != nil {
+		return nil, err
+	}
+	return protoadapt.MessageV1Of(mt.New().Interface()), nil
+}
+
+// grpcStatusDetails encodes status details to json, details of unknown types are left as base64 encoded bytes
+func grpcStatusDetails(source grpcurl.DescriptorSource, st *status.Status) []string {
+	details := st.Proto().GetDetails()
+	res := make([]string, len(details))
+	marshaler := protojson.MarshalOptions{Resolver: grpcTypeResolver{source}}
+	for i, detail := range details {
+		b, err := marshaler.Marshal(detail)
+		if err != nil {
+			b, _ = json.Marshal(map[string]any{
+				"@type": detail.GetTypeUrl(),
+				"value": detail.GetValue(),
+			})
+		}
+		res[i] = string(b)
+	}
+	return res
+}
+
+// grpcCodeName returns canonical name of code, like NOT_FOUND
+func grpcCodeName(c codes.Code) string {
+	if name, ok := code.Code_name[int32(c)]; ok {
+		return name
+	}
+	return c.String()
+}
+
+func metadataKVs(md metadata.MD) []database.KV {
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	kvs := make([]database.KV, 0, len(md))
+	for _, k := range keys {
+		for _, v := range md[k] {
+			kvs = append(kvs, database.KV{
+				Key:   k,
+				Value: v,
+			})
+		}
+	}
+	return kvs
+}
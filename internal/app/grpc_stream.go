@@ -0,0 +1,120 @@
+package app
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// grpcStream supplies request messages to call. Messages of bidirectional streams can be sent
+// after call is started, until stream is closed by user or call ends.
+type grpcStream struct {
+	parser grpcurl.RequestParser
+	bidi   bool
+
+	mu       sync.Mutex // NOTE: guards send side closing
+	closed   bool
+	send     chan string
+	done     chan struct{}
+	finished sync.Once
+}
+
+func newGRPCParser(payload string) grpcurl.RequestParser {
+	return grpcurl.NewJSONRequestParserWithUnmarshaler(strings.NewReader(payload), jsonpb.Unmarshaler{})
+}
+
+func newGRPCStream(payload string) *grpcStream {
+	return &grpcStream{
+		parser: newGRPCParser(payload),
+		send:   make(chan string),
+		done:   make(chan struct{}),
+	}
+}
+
+// next is grpcurl.RequestSupplier, payload may contain several json messages for client streams
+func (s *grpcStream) next(msg proto.Message) error {
+	for {
+		err := s.parser.Next(msg)
+		if err != io.EOF || !s.bidi {
+			return err
+		}
+
+		select {
+		case payload, ok := <-s.send:
+			if !ok {
+				return io.EOF
+			}
+			s.parser = newGRPCParser(payload)
+		case <-s.done:
+			return io.EOF
+		}
+	}
+}
+
+func (s *grpcStream) push(payload string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("stream is closed")
+	}
+
+	select {
+	case s.send <- payload:
+		return nil
+	case <-s.done:
+		return errors.New("call is finished")
+	}
+}
+
+func (s *grpcStream) closeSend() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.send)
+	}
+}
+
+// finish is called when call ends, so that pending sends are released
+func (s *grpcStream) finish() {
+	s.finished.Do(func() { close(s.done) })
+}
+
+func (a *App) getGRPCStream(id database.RequestID) (*grpcStream, error) {
+	a.grpcStreamsMu.Lock()
+	defer a.grpcStreamsMu.Unlock()
+
+	stream, ok := a.grpcStreams[id]
+	if !ok {
+		return nil, errors.Errorf("no open bidirectional stream for request %q", id)
+	}
+	return stream, nil
+}
+
+// GRPCStreamSend sends json messages from payload to open bidirectional stream of request
+func (a *App) GRPCStreamSend(requestID, payload string) error {
+	stream, err := a.getGRPCStream(database.RequestID(requestID))
+	if err != nil {
+		return err
+	}
+	return stream.push(payload)
+}
+
+// GRPCStreamClose closes sending side of bidirectional stream, call ends when server closes its side
+func (a *App) GRPCStreamClose(requestID string) error {
+	stream, err := a.getGRPCStream(database.RequestID(requestID))
+	if err != nil {
+		return err
+	}
+	stream.closeSend()
+	return nil
+}
@@ -0,0 +1,439 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type grpcPayloadError struct {
+	// Path is location of invalid value like `items[0].name`, prefixed with message index like `[1].name`
+	// if payload contains several messages
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	// From and To are byte offsets of invalid value in payload
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// payloadValidator walks json tokens of payload along with message descriptor
+type payloadValidator struct {
+	payload string
+	dec     *json.Decoder
+	errs    []grpcPayloadError
+	// message is index of currently checked message, messages hold it for every error
+	message  int
+	messages []int
+}
+
+func (v *payloadValidator) fail(path string, from, to int, format string, args ...any) {
+	v.errs = append(v.errs, grpcPayloadError{path, fmt.Sprintf(format, args...), from, to})
+	v.messages = append(v.messages, v.message)
+}
+
+// token returns next token along with its offsets
+func (v *payloadValidator) token() (json.Token, int, int, error) {
+	from := int(v.dec.InputOffset())
+	for from < len(v.payload) && strings.IndexByte(" \t\r\n,:", v.payload[from]) != -1 {
+		from++
+	}
+	tok, err := v.dec.Token()
+	return tok, from, int(v.dec.InputOffset()), err
+}
+
+// skip consumes value which starts with tok, returns end offset
+func (v *payloadValidator) skip(tok json.Token) (int, error) {
+	if tok != json.Delim('{') && tok != json.Delim('[') {
+		return int(v.dec.InputOffset()), nil
+	}
+
+	for depth := 1; depth > 0; {
+		tok, _, _, err := v.token()
+		if err != nil {
+			return 0, err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return int(v.dec.InputOffset()), nil
+}
+
+func jsonTypeName(tok json.Token) string {
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			return "object"
+		}
+		return "array"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	default:
+		return "null"
+	}
+}
+
+func (v *payloadValidator) nextMessage(path string, md protoreflect.MessageDescriptor) error {
+	tok, from, _, err := v.token()
+	if err != nil {
+		return err
+	}
+	return v.messageValue(path, md, tok, from)
+}
+
+// _wellKnownJSON are types having special json form
+var _wellKnownJSON = map[protoreflect.FullName]struct{}{
+	"google.protobuf.Any":         {},
+	"google.protobuf.Timestamp":   {},
+	"google.protobuf.Duration":    {},
+	"google.protobuf.FieldMask":   {},
+	"google.protobuf.Struct":      {},
+	"google.protobuf.Value":       {},
+	"google.protobuf.ListValue":   {},
+	"google.protobuf.Empty":       {},
+	"google.protobuf.BoolValue":   {},
+	"google.protobuf.BytesValue":  {},
+	"google.protobuf.StringValue": {},
+	"google.protobuf.DoubleValue": {},
+	"google.protobuf.FloatValue":  {},
+	"google.protobuf.Int32Value":  {},
+	"google.protobuf.Int64Value":  {},
+	"google.protobuf.UInt32Value": {},
+	"google.protobuf.UInt64Value": {},
+}
+
+func (v *payloadValidator) messageValue(path string, md protoreflect.MessageDescriptor, tok json.Token, from int) error {
+	if _, ok := _wellKnownJSON[md.FullName()]; ok {
+		// NOTE: well-known types have special json forms, they are checked by jsonpb
+		_, err := v.skip(tok)
+		return err
+	}
+
+	if tok != json.Delim('{') {
+		to, err := v.skip(tok)
+		if err != nil {
+			return err
+		}
+		v.fail(path, from, to, "expected object for %s, got %s", md.FullName(), jsonTypeName(tok))
+		return nil
+	}
+
+	oneofs := map[protoreflect.FullName]string{}
+	for v.dec.More() {
+		tok, keyFrom, keyTo, err := v.token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+
+		fd := md.Fields().ByJSONName(key)
+		if fd == nil {
+			fd = md.Fields().ByName(protoreflect.Name(key))
+		}
+		if fd == nil {
+			tok, _, _, err := v.token()
+			if err != nil {
+				return err
+			}
+			if _, err := v.skip(tok); err != nil {
+				return err
+			}
+			v.fail(jsonPathKey(path, key), keyFrom, keyTo, "unknown field %q in %s", key, md.FullName())
+			continue
+		}
+
+		fieldPath := jsonPathKey(path, key)
+		tok, valueFrom, _, err := v.token()
+		if err != nil {
+			return err
+		}
+
+		if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() && tok != nil {
+			if other, ok := oneofs[oneof.FullName()]; ok {
+				v.fail(fieldPath, keyFrom, keyTo, "oneof %s is already set by field %q", oneof.Name(), other)
+			}
+			oneofs[oneof.FullName()] = key
+		}
+
+		if err := v.field(fieldPath, fd, tok, valueFrom); err != nil {
+			return err
+		}
+	}
+	_, _, _, err := v.token() // NOTE: closing brace
+	return err
+}
+
+func (v *payloadValidator) field(path string, fd protoreflect.FieldDescriptor, tok json.Token, from int) error {
+	switch {
+	case tok == nil:
+		return nil
+	case fd.IsMap():
+		if tok != json.Delim('{') {
+			to, err := v.skip(tok)
+			if err != nil {
+				return err
+			}
+			v.fail(path, from, to, "expected object for map, got %s", jsonTypeName(tok))
+			return nil
+		}
+
+		for v.dec.More() {
+			tok, keyFrom, keyTo, err := v.token()
+			if err != nil {
+				return err
+			}
+			key := tok.(string)
+			if msg := checkMapKey(fd.MapKey(), key); msg != "" {
+				v.fail(jsonPathKey(path, key), keyFrom, keyTo, "%s", msg)
+			}
+
+			tok, valueFrom, _, err := v.token()
+			if err != nil {
+				return err
+			}
+			if err := v.single(jsonPathKey(path, key), fd.MapValue(), tok, valueFrom); err != nil {
+				return err
+			}
+		}
+		_, _, _, err := v.token()
+		return err
+	case fd.IsList():
+		if tok != json.Delim('[') {
+			to, err := v.skip(tok)
+			if err != nil {
+				return err
+			}
+			v.fail(path, from, to, "expected array, got %s", jsonTypeName(tok))
+			return nil
+		}
+
+		for i := 0; v.dec.More(); i++ {
+			tok, itemFrom, _, err := v.token()
+			if err != nil {
+				return err
+			}
+			if err := v.single(path+"["+strconv.Itoa(i)+"]", fd, tok, itemFrom); err != nil {
+				return err
+			}
+		}
+		_, _, _, err := v.token()
+		return err
+	default:
+		return v.single(path, fd, tok, from)
+	}
+}
+
+func checkMapKey(fd protoreflect.FieldDescriptor, key string) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if key != "true" && key != "false" {
+			return fmt.Sprintf("invalid map key %q, expected true or false", key)
+		}
+	case protoreflect.StringKind:
+	default:
+		if msg := checkInteger(fd.Kind(), key); msg != "" {
+			return "invalid map key: " + msg
+		}
+	}
+	return ""
+}
+
+// checkInteger checks that s is integer in range of kind
+func checkInteger(kind protoreflect.Kind, s string) string {
+	var bits int
+	signed := true
+	switch kind {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		bits = 32
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		bits = 64
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		bits, signed = 32, false
+	default:
+		bits, signed = 64, false
+	}
+
+	var err error
+	if signed {
+		_, err = strconv.ParseInt(s, 10, bits)
+	} else {
+		_, err = strconv.ParseUint(s, 10, bits)
+	}
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return fmt.Sprintf("%s is out of range of %s", s, kind)
+		}
+		return fmt.Sprintf("%s is not a valid %s", s, kind)
+	}
+	return ""
+}
+
+func (v *payloadValidator) single(path string, fd protoreflect.FieldDescriptor, tok json.Token, from int) error {
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		if tok == nil {
+			return nil
+		}
+		return v.messageValue(path, fd.Message(), tok, from)
+	}
+
+	to, err := v.skip(tok)
+	if err != nil {
+		return err
+	}
+	fail := func(format string, args ...any) {
+		v.fail(path, from, to, format, args...)
+	}
+	wrongType := func(expected string) {
+		fail("expected %s for %s field, got %s", expected, fd.Kind(), jsonTypeName(tok))
+	}
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if _, ok := tok.(bool); !ok && tok != nil {
+			wrongType("boolean")
+		}
+	case protoreflect.StringKind:
+		if _, ok := tok.(string); !ok && tok != nil {
+			wrongType("string")
+		}
+	case protoreflect.BytesKind:
+		s, ok := tok.(string)
+		switch {
+		case tok == nil:
+		case !ok:
+			wrongType("base64 string")
+		default:
+			_, errStd := base64.StdEncoding.DecodeString(s)
+			_, errURL := base64.URLEncoding.DecodeString(s)
+			_, errRaw := base64.RawStdEncoding.DecodeString(s)
+			_, errRawURL := base64.RawURLEncoding.DecodeString(s)
+			if errStd != nil && errURL != nil && errRaw != nil && errRawURL != nil {
+				fail("invalid base64 value")
+			}
+		}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		switch tok := tok.(type) {
+		case nil, json.Number:
+		case string:
+			if _, err := strconv.ParseFloat(tok, 64); err != nil && tok != "NaN" && tok != "Infinity" && tok != "-Infinity" {
+				fail("%q is not a valid number", tok)
+			}
+		default:
+			wrongType("number")
+		}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		switch tok := tok.(type) {
+		case nil:
+		case string:
+			if values.ByName(protoreflect.Name(tok)) == nil {
+				names := make([]string, values.Len())
+				for i := range values.Len() {
+					names[i] = string(values.Get(i).Name())
+				}
+				fail("invalid value %q for enum %s, expected one of %s", tok, fd.Enum().FullName(), strings.Join(names, ", "))
+			}
+		case json.Number:
+			if msg := checkInteger(protoreflect.Int32Kind, tok.String()); msg != "" {
+				fail("invalid enum number: %s", msg)
+			}
+		default:
+			wrongType("enum name or number")
+		}
+	default: // NOTE: integers
+		switch tok := tok.(type) {
+		case nil:
+		case json.Number:
+			if msg := checkInteger(fd.Kind(), tok.String()); msg != "" {
+				fail("%s", msg)
+			}
+		case string:
+			if msg := checkInteger(fd.Kind(), tok); msg != "" {
+				fail("%s", msg)
+			}
+		default:
+			wrongType("integer")
+		}
+	}
+	return nil
+}
+
+// validateGRPCPayload checks payload, which may contain several json messages, against input message descriptor.
+// Empty payload is valid as empty message.
+func validateGRPCPayload(md protoreflect.MessageDescriptor, payload string) []grpcPayloadError {
+	dec := json.NewDecoder(strings.NewReader(payload))
+	dec.UseNumber()
+	v := &payloadValidator{payload: payload, dec: dec, errs: []grpcPayloadError{}}
+
+	type segment struct{ from, to int }
+	var segments []segment
+	for ; ; v.message++ {
+		from := int(dec.InputOffset())
+		err := v.nextMessage("", md)
+		if err == io.EOF {
+			break // NOTE: empty payload is sent as empty message, same as {}
+		}
+		var syntaxErr *json.SyntaxError
+		switch {
+		case errors.As(err, &syntaxErr):
+			v.fail("", int(syntaxErr.Offset), int(syntaxErr.Offset), "invalid json: %s", err.Error())
+			return v.errs
+		case err != nil:
+			v.fail("", len(payload), len(payload), "invalid json: %s", err.Error())
+			return v.errs
+		}
+		segments = append(segments, segment{from, int(dec.InputOffset())})
+	}
+
+	if len(v.errs) == 0 {
+		// NOTE: final check by jsonpb catches the rest, like invalid well-known type values
+		for i, seg := range segments {
+			v.message = i
+			msg := protoadapt.MessageV1Of(dynamicpb.NewMessage(md))
+			if err := (&jsonpb.Unmarshaler{}).Unmarshal(strings.NewReader(payload[seg.from:seg.to]), msg); err != nil {
+				v.fail("", seg.from, seg.to, "%s", err.Error())
+			}
+		}
+	}
+
+	if len(segments) > 1 {
+		for i := range v.errs {
+			prefix := "[" + strconv.Itoa(v.messages[i]) + "]"
+			if path := v.errs[i].Path; path == "" || path[0] == '[' {
+				v.errs[i].Path = prefix + path
+			} else {
+				v.errs[i].Path = prefix + "." + path
+			}
+		}
+	}
+	return v.errs
+}
+
+// GRPCQueryValidate checks payload of request method, returned errors are empty if payload is valid
+func (a *App) GRPCQueryValidate(
+	requestID string,
+	Method string, // NOTE: fully qualified
+	Payload string,
+) ([]grpcPayloadError, error) {
+	md, err := a.grpcInputType(requestID, Method)
+	if err != nil {
+		return nil, err
+	}
+	return validateGRPCPayload(md, Payload), nil
+}
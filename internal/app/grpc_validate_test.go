@@ -0,0 +1,197 @@
+package app
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb" // NOTE: registers google/protobuf/timestamp.proto
+)
+
+// testRequestDescriptor returns descriptor of message test.Request covering all kinds of fields:
+//
+//	enum Status { STATUS_UNSPECIFIED = 0; STATUS_OK = 1; }
+//	message Item { string name = 1; }
+//	message Request {
+//	  int32 count = 1;
+//	  int64 big = 2;
+//	  uint32 small = 3;
+//	  string user_email = 4;
+//	  bytes data = 5;
+//	  double ratio = 6;
+//	  bool flag = 7;
+//	  Status status = 8;
+//	  repeated Item items = 9;
+//	  map<int32, string> labels = 10;
+//	  google.protobuf.Timestamp at = 11;
+//	  oneof choice { string a = 12; int32 b = 13; }
+//	  Request child = 14;
+//	}
+func testRequestDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   typ.Enum(),
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+	repeated := func(fd *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		fd.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		return fd
+	}
+	oneof := func(fd *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		fd.OneofIndex = proto.Int32(0)
+		return fd
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Status"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("STATUS_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("STATUS_OK"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Item"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				},
+			},
+			{
+				Name: proto.String("Request"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("count", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+					field("big", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+					field("small", 3, descriptorpb.FieldDescriptorProto_TYPE_UINT32, ""),
+					field("user_email", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("data", 5, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+					field("ratio", 6, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, ""),
+					field("flag", 7, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+					field("status", 8, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".test.Status"),
+					repeated(field("items", 9, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Item")),
+					repeated(field("labels", 10, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Request.LabelsEntry")),
+					field("at", 11, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					oneof(field("a", 12, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")),
+					oneof(field("b", 13, descriptorpb.FieldDescriptorProto_TYPE_INT32, "")),
+					field("child", 14, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Request"),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("LabelsEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+						field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				}},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("choice")}},
+			},
+		},
+	}
+
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().ByName("Request")
+}
+
+func TestValidateGRPCPayload(t *testing.T) {
+	md := testRequestDescriptor(t)
+
+	for _, tc := range []struct {
+		name    string
+		payload string
+		want    []grpcPayloadError
+	}{
+		{"empty", "", nil},
+		{"only whitespace", " \n\t", nil},
+		{"empty object", "{}", nil},
+		{"all fields", `{
+			"count": 1, "big": "9007199254740993", "small": 2, "userEmail": "a@b.c", "data": "aGk=",
+			"ratio": "NaN", "flag": true, "status": "STATUS_OK", "items": [{"name": "x"}],
+			"labels": {"1": "x"}, "at": "2024-01-01T00:00:00Z", "a": "x", "child": {"count": 2}
+		}`, nil},
+		{"proto field names", `{"user_email": "a@b.c"}`, nil},
+		{"nulls", `{"count": null, "items": null, "child": null, "a": null, "b": 1}`, nil},
+		{"enum number", `{"status": 1}`, nil},
+		{"several messages", `{"count": 1} {"count": 2}`, nil},
+		{"wrong type", `{"count": "x"}`, []grpcPayloadError{
+			{"count", "x is not a valid int32", 10, 13},
+		}},
+		{"negative unsigned", `{"small": -1}`, []grpcPayloadError{
+			{"small", "-1 is not a valid uint32", 10, 12},
+		}},
+		{"out of range", `{"count": 3000000000}`, []grpcPayloadError{
+			{"count", "3000000000 is out of range of int32", 10, 20},
+		}},
+		{"unknown field", `{"nope": 1}`, []grpcPayloadError{
+			{"nope", `unknown field "nope" in test.Request`, 1, 7},
+		}},
+		{"not object", `[1]`, []grpcPayloadError{
+			{"", "expected object for test.Request, got array", 0, 3},
+		}},
+		{"nested path", `{"items": [{"name": "x"}, {"name": 1}]}`, []grpcPayloadError{
+			{"items[1].name", "expected string for string field, got number", 35, 36},
+		}},
+		{"invalid enum", `{"status": "NOPE"}`, []grpcPayloadError{
+			{"status", `invalid value "NOPE" for enum test.Status, expected one of STATUS_UNSPECIFIED, STATUS_OK`, 11, 17},
+		}},
+		{"invalid map key", `{"labels": {"x": "y"}}`, []grpcPayloadError{
+			{"labels.x", "invalid map key: x is not a valid int32", 12, 15},
+		}},
+		{"invalid bytes", `{"data": "!"}`, []grpcPayloadError{
+			{"data", "invalid base64 value", 9, 12},
+		}},
+		{"oneof set twice", `{"a": "x", "b": 1}`, []grpcPayloadError{
+			{"b", `oneof choice is already set by field "a"`, 11, 14},
+		}},
+		{"errors of several messages", `{} {"count": true} {"nope": 1}`, []grpcPayloadError{
+			{"[1].count", "expected integer for int32 field, got boolean", 13, 17},
+			{"[2].nope", `unknown field "nope" in test.Request`, 20, 26},
+		}},
+		{"invalid json", `{"count": }`, []grpcPayloadError{
+			{"", "invalid json: missing value after object key", 11, 11},
+		}},
+		{"unexpected end", `{"count": 1`, []grpcPayloadError{
+			{"", "invalid json: unexpected end of JSON input", 11, 11},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := validateGRPCPayload(md, tc.payload)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("error %d: got %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateGRPCPayloadWellKnown(t *testing.T) {
+	md := testRequestDescriptor(t)
+
+	// NOTE: well-known types are checked by jsonpb, which reports whole message
+	got := validateGRPCPayload(md, `{"at": "yesterday"}`)
+	if len(got) != 1 || got[0].From != 0 || got[0].To != 19 {
+		t.Fatalf("got %+v, want single error of whole message", got)
+	}
+}
@@ -0,0 +1,534 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/pkg/errors"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// _grpcDefaultMaxRecvSize is grpc-go default limit of received message size, applied if request has no limit
+const _grpcDefaultMaxRecvSize = 4 << 20
+
+const (
+	frameFlagCompressed  = 0x01
+	frameFlagConnectEnd  = 0x02 // NOTE: Connect end of stream message
+	frameFlagWebTrailers = 0x80 // NOTE: gRPC-Web trailers
+)
+
+func grpcFrame(flag byte, data []byte) []byte {
+	frame := make([]byte, 5, 5+len(data))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	return append(frame, data...)
+}
+
+// grpcWebTextReader decodes grpc-web-text response. Response may consist of several base64 chunks
+// each with its own padding, but since every 4 chars group is decoded independently, groups are decoded one by one.
+type grpcWebTextReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func (t *grpcWebTextReader) Read(p []byte) (int, error) {
+	if len(t.buf) == 0 {
+		group := make([]byte, 0, 4)
+		for len(group) < 4 {
+			b, err := t.r.ReadByte()
+			if err != nil {
+				if err == io.EOF && len(group) > 0 {
+					return 0, io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+			if !unicode.IsSpace(rune(b)) {
+				group = append(group, b)
+			}
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(string(group))
+		if err != nil {
+			return 0, errors.Wrap(err, "decode grpc-web-text")
+		}
+		t.buf = decoded
+	}
+
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+	return n, nil
+}
+
+// grpcHTTPCode maps http status of failed response to grpc code
+func grpcHTTPCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.Internal
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+// _connectCodes are Connect error codes, which are lowercase grpc code names
+var _connectCodes = func() map[string]codes.Code {
+	m := map[string]codes.Code{"canceled": codes.Canceled}
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		m[strings.ToLower(grpcCodeName(c))] = c
+	}
+	return m
+}()
+
+// connectErrorStatus decodes Connect error json
+func connectErrorStatus(data []byte, httpStatus int) *status.Status {
+	var e struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Details []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil || e.Code == "" {
+		return status.New(grpcHTTPCode(httpStatus), strings.TrimSpace(string(data)))
+	}
+
+	code, ok := _connectCodes[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+	st := &spb.Status{Code: int32(code), Message: e.Message}
+	for _, detail := range e.Details {
+		// NOTE: Connect encodes values without padding
+		value, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(detail.Value, "="))
+		if err != nil {
+			continue
+		}
+		st.Details = append(st.Details, &anypb.Any{TypeUrl: "type.googleapis.com/" + detail.Type, Value: value})
+	}
+	return status.FromProto(st)
+}
+
+// grpcStatusFromMD takes status from gRPC-Web headers or trailers, status keys are removed from md
+func grpcStatusFromMD(md metadata.MD) (*status.Status, bool) {
+	codeValues := md.Get("grpc-status")
+	if len(codeValues) == 0 {
+		return nil, false
+	}
+	defer func() {
+		delete(md, "grpc-status")
+		delete(md, "grpc-message")
+		delete(md, "grpc-status-details-bin")
+	}()
+
+	code, err := strconv.Atoi(codeValues[0])
+	if err != nil {
+		return status.Newf(codes.Internal, "invalid grpc-status %q", codeValues[0]), true
+	}
+
+	var message string
+	if vs := md.Get("grpc-message"); len(vs) > 0 {
+		message, _ = url.PathUnescape(vs[0])
+	}
+
+	if vs := md.Get("grpc-status-details-bin"); len(vs) > 0 {
+		b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(vs[0], "="))
+		var st spb.Status
+		if err == nil && proto.Unmarshal(b, &st) == nil && st.GetCode() == int32(code) {
+			return status.FromProto(&st), true
+		}
+	}
+	return status.New(codes.Code(code), message), true
+}
+
+// grpcHTTPCall is call of unary or server streaming method over HTTP/1.1 using gRPC-Web or Connect protocol
+type grpcHTTPCall struct {
+	a        *App
+	id       database.RequestID
+	req      database.GRPCRequest
+	resolver grpcTypeResolver
+	output   protoreflect.MessageDescriptor
+
+	headers, trailers metadata.MD
+	status            *status.Status
+	messages          []database.GRPCMessage
+}
+
+func (c *grpcHTTPCall) message(data []byte, isJSON bool) error {
+	msg := dynamicpb.NewMessage(c.output)
+	if isJSON {
+		unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true, AnyResolver: c.resolver}
+		if err := unmarshaler.Unmarshal(bytes.NewReader(data), protoadapt.MessageV1Of(msg)); err != nil {
+			return errors.Wrap(err, "decode response message")
+		}
+	} else if err := proto.Unmarshal(data, msg); err != nil {
+		return errors.Wrap(err, "decode response message")
+	}
+
+	s, err := (&jsonpb.Marshaler{AnyResolver: c.resolver}).MarshalToString(protoadapt.MessageV1Of(msg))
+	if err != nil {
+		return errors.Wrap(err, "encode response message")
+	}
+	c.messages = append(c.messages, c.a.grpcMessage(c.id, s))
+	return nil
+}
+
+func (c *grpcHTTPCall) maxRecvSize() int {
+	if c.req.MaxRecvSize > 0 {
+		return c.req.MaxRecvSize
+	}
+	return _grpcDefaultMaxRecvSize
+}
+
+// readFrames reads length prefixed messages, end is called for frame having endFlag
+func (c *grpcHTTPCall) readFrames(r io.Reader, endFlag byte, isJSON bool, end func([]byte) error) error {
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.Wrap(err, "read frame")
+		}
+
+		size := binary.BigEndian.Uint32(header[1:])
+		if int64(size) > int64(c.maxRecvSize()) {
+			c.status = status.Newf(codes.ResourceExhausted, "received message larger than max (%d vs. %d)", size, c.maxRecvSize())
+			return nil
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return errors.Wrap(err, "read frame")
+		}
+
+		switch flag := header[0]; {
+		case flag&endFlag != 0:
+			if err := end(data); err != nil {
+				return err
+			}
+		case flag&frameFlagCompressed != 0:
+			return errors.New("compressed messages are not supported")
+		default:
+			if err := c.message(data, isJSON); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func headerMD(h http.Header) metadata.MD {
+	md := make(metadata.MD, len(h))
+	for k, vs := range h {
+		md[strings.ToLower(k)] = vs
+	}
+	return md
+}
+
+func (c *grpcHTTPCall) readWeb(resp *http.Response) error {
+	c.headers = headerMD(resp.Header)
+	if st, ok := grpcStatusFromMD(c.headers); ok {
+		c.status = st // NOTE: trailers-only response
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		c.status = status.New(grpcHTTPCode(resp.StatusCode), resp.Status)
+		return nil
+	}
+
+	var body io.Reader = resp.Body
+	if c.req.Protocol == database.GRPCProtocolWebText {
+		body = &grpcWebTextReader{r: bufio.NewReader(resp.Body)}
+	}
+	if err := c.readFrames(body, frameFlagWebTrailers, false, func(data []byte) error {
+		c.trailers = metadata.MD{}
+		for _, line := range strings.Split(string(data), "\r\n") {
+			if k, v, ok := strings.Cut(line, ":"); ok {
+				c.trailers.Append(strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v))
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if c.status == nil {
+		st, ok := grpcStatusFromMD(c.trailers)
+		if !ok {
+			st = status.New(codes.Internal, "server closed the stream without sending trailers")
+		}
+		c.status = st
+	}
+	return nil
+}
+
+func (c *grpcHTTPCall) readConnect(resp *http.Response, streaming bool) error {
+	c.headers, c.trailers = metadata.MD{}, metadata.MD{}
+	for k, vs := range headerMD(resp.Header) {
+		if trailer, ok := strings.CutPrefix(k, "trailer-"); ok {
+			c.trailers[trailer] = vs
+		} else {
+			c.headers[k] = vs
+		}
+	}
+
+	isJSON := c.req.Protocol == database.GRPCProtocolConnectJSON
+
+	if resp.StatusCode != http.StatusOK || !streaming {
+		data, err := io.ReadAll(io.LimitReader(resp.Body, int64(c.maxRecvSize())+1))
+		if err != nil {
+			return errors.Wrap(err, "read response")
+		}
+
+		switch {
+		case len(data) > c.maxRecvSize():
+			c.status = status.Newf(codes.ResourceExhausted, "received message larger than max (%d)", c.maxRecvSize())
+		case resp.StatusCode != http.StatusOK:
+			c.status = connectErrorStatus(data, resp.StatusCode)
+		default:
+			c.status = status.New(codes.OK, "")
+			return c.message(data, isJSON)
+		}
+		return nil
+	}
+
+	if err := c.readFrames(resp.Body, frameFlagConnectEnd, isJSON, func(data []byte) error {
+		var end struct {
+			Error    json.RawMessage     `json:"error"`
+			Metadata map[string][]string `json:"metadata"`
+		}
+		if err := json.Unmarshal(data, &end); err != nil {
+			return errors.Wrap(err, "decode end of stream")
+		}
+
+		for k, vs := range end.Metadata {
+			c.trailers[strings.ToLower(k)] = vs
+		}
+		if len(end.Error) > 0 {
+			c.status = connectErrorStatus(end.Error, resp.StatusCode)
+		} else {
+			c.status = status.New(codes.OK, "")
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if c.status == nil {
+		c.status = status.New(codes.Internal, "server closed the stream without end of stream message")
+	}
+	return nil
+}
+
+func grpcFindMethod(source grpcurl.DescriptorSource, name string) (*desc.MethodDescriptor, error) {
+	i := strings.LastIndexAny(name, "/.")
+	if i == -1 {
+		return nil, errors.Errorf("invalid method name %q", name)
+	}
+
+	dsc, err := source.FindSymbol(name[:i])
+	if err != nil {
+		return nil, errors.Wrap(err, "find service")
+	}
+	service, ok := dsc.(*desc.ServiceDescriptor)
+	if !ok {
+		return nil, errors.Errorf("%s is not a service", name[:i])
+	}
+
+	method := service.FindMethodByName(name[i+1:])
+	if method == nil {
+		return nil, errors.Errorf("service %s has no method %s", name[:i], name[i+1:])
+	}
+	return method, nil
+}
+
+// grpcHTTPClient returns client dialing request target through ssh tunnel if needed and base url of target
+func (a *App) grpcHTTPClient(req database.GRPCRequest) (*http.Client, *url.URL, error) {
+	base := req.Target
+	if !strings.Contains(base, "://") {
+		if req.TLS != nil {
+			base = "https://" + base
+		} else {
+			base = "http://" + base
+		}
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parse target")
+	}
+
+	transport := &http.Transport{}
+	if u.Scheme == "https" && req.TLS != nil {
+		cfg, err := grpcTLSConfig(req, u.Hostname())
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "load tls config")
+		}
+		transport.TLSClientConfig = cfg
+	}
+
+	if req.SSH != nil {
+		port := u.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+		}
+		addr, err := a.tunnel(req.SSH, net.JoinHostPort(u.Hostname(), port))
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "open ssh tunnel")
+		}
+		dialer := &net.Dialer{}
+		transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+
+	return &http.Client{Transport: transport}, u, nil
+}
+
+// sendGRPCHTTP calls unary or server streaming method using gRPC-Web or Connect protocol
+func (a *App) sendGRPCHTTP(
+	id database.RequestID,
+	req database.GRPCRequest,
+	source grpcurl.DescriptorSource,
+) (database.GRPCResponse, error) {
+	method, err := grpcFindMethod(source, req.Method)
+	if err != nil {
+		return database.GRPCResponse{}, err
+	}
+	if method.IsClientStreaming() {
+		return database.GRPCResponse{}, errors.Errorf("client streaming is not supported over %s", req.Protocol)
+	}
+	streaming := method.IsServerStreaming()
+
+	input := dynamicpb.NewMessage(method.GetInputType().UnwrapMessage())
+	parser := newGRPCParser(req.Payload)
+	if err := parser.Next(protoadapt.MessageV1Of(input)); err != nil && err != io.EOF {
+		return database.GRPCResponse{}, errors.Wrap(err, "parse payload")
+	}
+	if err := parser.Next(protoadapt.MessageV1Of(dynamicpb.NewMessage(input.Descriptor()))); err != io.EOF {
+		return database.GRPCResponse{}, errors.New("payload must contain single message")
+	}
+
+	resolver := grpcTypeResolver{source}
+	var body []byte
+	if req.Protocol == database.GRPCProtocolConnectJSON {
+		s, err := (&jsonpb.Marshaler{AnyResolver: resolver}).MarshalToString(protoadapt.MessageV1Of(input))
+		if err != nil {
+			return database.GRPCResponse{}, errors.Wrap(err, "encode request message")
+		}
+		body = []byte(s)
+	} else if body, err = proto.Marshal(input); err != nil {
+		return database.GRPCResponse{}, errors.Wrap(err, "encode request message")
+	}
+	if req.MaxSendSize > 0 && len(body) > req.MaxSendSize {
+		return database.GRPCResponse{}, errors.Errorf("request message is %d bytes, max send size is %d", len(body), req.MaxSendSize)
+	}
+
+	var contentType string
+	switch req.Protocol {
+	case database.GRPCProtocolWeb:
+		contentType = "application/grpc-web+proto"
+		body = grpcFrame(0, body)
+	case database.GRPCProtocolWebText:
+		contentType = "application/grpc-web-text"
+		body = []byte(base64.StdEncoding.EncodeToString(grpcFrame(0, body)))
+	case database.GRPCProtocolConnectJSON, database.GRPCProtocolConnectProto:
+		contentType = "application/" + strings.TrimPrefix(string(req.Protocol), "connect_")
+		if streaming {
+			contentType = "application/connect+" + strings.TrimPrefix(string(req.Protocol), "connect_")
+			body = grpcFrame(0, body)
+		}
+	default:
+		return database.GRPCResponse{}, errors.Errorf("unknown protocol %q", req.Protocol)
+	}
+
+	client, u, err := a.grpcHTTPClient(req)
+	if err != nil {
+		return database.GRPCResponse{}, err
+	}
+	defer client.CloseIdleConnections()
+
+	ctx, cancel := a.grpcContext(req)
+	defer cancel()
+
+	u = u.JoinPath(method.GetService().GetFullyQualifiedName(), method.GetName())
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return database.GRPCResponse{}, errors.Wrap(err, "create request")
+	}
+	for _, kv := range req.Metadata {
+		httpReq.Header.Add(kv.Key, kv.Value)
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	if req.Authority != "" {
+		httpReq.Host = req.Authority
+	}
+
+	isWeb := req.Protocol == database.GRPCProtocolWeb || req.Protocol == database.GRPCProtocolWebText
+	if isWeb {
+		httpReq.Header.Set("Accept", contentType)
+		httpReq.Header.Set("X-Grpc-Web", "1")
+		if req.Deadline > 0 {
+			httpReq.Header.Set("Grpc-Timeout", strconv.FormatInt(req.Deadline.Milliseconds(), 10)+"m")
+		}
+	} else {
+		httpReq.Header.Set("Connect-Protocol-Version", "1")
+		if req.Deadline > 0 {
+			httpReq.Header.Set("Connect-Timeout-Ms", strconv.FormatInt(req.Deadline.Milliseconds(), 10))
+		}
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return database.GRPCResponse{}, errors.Wrap(err, "send request")
+	}
+	defer resp.Body.Close()
+
+	call := &grpcHTTPCall{
+		a:        a,
+		id:       id,
+		req:      req,
+		resolver: resolver,
+		output:   method.GetOutputType().UnwrapMessage(),
+	}
+	if isWeb {
+		err = call.readWeb(resp)
+	} else {
+		err = call.readConnect(resp, streaming)
+	}
+	if err != nil {
+		return database.GRPCResponse{}, err
+	}
+
+	return grpcResponse(source, call.status, call.headers, call.trailers, call.messages), nil
+}
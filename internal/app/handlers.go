@@ -121,6 +121,18 @@ func (a *App) Get(id string) (GetResponse, error) {
 	return GetResponse{request, history}, nil
 }
 
+// historyAt returns history entry by index counted back from the latest sent one, 0 is the latest
+func historyAt(request database.Request, index int) (database.HistoryEntry, error) {
+	history := slices.Clone(request.History)
+	slices.SortStableFunc(history, func(i, j database.HistoryEntry) int {
+		return i.SentAt.Compare(j.SentAt)
+	})
+	if index < 0 || index >= len(history) {
+		return database.HistoryEntry{}, errors.Errorf("history entry %d not found, request %q has %d entries", index, request.ID, len(history))
+	}
+	return history[len(history)-1-index], nil
+}
+
 type ResponseNewRequest struct {
 	ID database.RequestID `json:"id"`
 }
@@ -143,16 +155,27 @@ func (a *App) Create(
 		}
 	case database.KindSQL:
 		req = database.SQLRequest{
-			"",                // DSN // TODO: insert last dsn used
-			database.Postgres, // Database
-			"",                // Query
+			"",                   // DSN // TODO: insert last dsn used
+			database.Postgres,    // Database
+			"",                   // Query
+			database.SQLModeAuto, // Mode
+			nil,                  // Params
+			nil,                  // SSH
 		}
 	case database.KindGRPC:
 		req = database.GRPCRequest{
-			"",  // Target
-			"",  // Method
-			"",  // Payload
-			nil, // Metadata
+			"",                        // Target
+			"",                        // Method
+			"",                        // Payload
+			nil,                       // Metadata
+			nil,                       // SSH
+			database.GRPCProtocolGRPC, // Protocol
+			nil,                       // Protos
+			nil,                       // TLS
+			"",                        // Authority
+			0,                         // MaxRecvSize
+			0,                         // MaxSendSize
+			0,                         // Deadline
 		}
 	case database.KindJQ:
 		req = database.JQRequest{
@@ -164,11 +187,21 @@ func (a *App) Create(
 	"list": [1, 2, 3],
 	"null": null
 }`, // JSON
+			"",                    // Input
+			0,                     // InputIndex
+			nil,                   // Args
+			database.JQInputJSON,  // InputFormat
+			database.JQOutputJSON, // OutputFormat
 		}
 	case database.KindRedis:
 		req = database.RedisRequest{
-			"localhost:6379",
-			`KEYS`,
+			"localhost:6379",             // DSN
+			`KEYS`,                       // Query
+			nil,                          // SSH
+			false,                        // Transaction
+			database.RedisModeQuery,      // Mode
+			database.RedisSubscription{}, // Subscription
+			database.RedisDeployment{database.RedisDeploymentStandalone, "", nil}, // Deployment
 		}
 	case database.KindMarkdown:
 		req = database.MarkdownRequest{defaultMarkdown}
@@ -337,22 +370,27 @@ func (a *App) Perform(requestID string) (historyEntry, error) {
 			return nil, errors.Wrapf(err, "send http request id=%q", requestID)
 		}
 	case database.SQLRequest:
-		response, err = a.sendSQL(request)
+		response, err = a.sendSQL(database.RequestID(requestID), request)
 		if err != nil {
 			return nil, errors.Wrapf(err, "send sql request id=%q", requestID)
 		}
 	case database.GRPCRequest:
-		response, err = a.sendGRPC(request)
+		response, err = a.sendGRPC(database.RequestID(requestID), request)
 		if err != nil {
 			return nil, errors.Wrapf(err, "send grpc request id=%q", requestID)
 		}
 	case database.JQRequest:
-		response, err = sendJQ(a.ctx, request)
+		inputs, err := a.jqInputs(request)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get jq input id=%q", requestID)
+		}
+
+		response, err = a.sendJQ(inputs, request)
 		if err != nil {
 			return nil, errors.Wrapf(err, "send jq request id=%q", requestID)
 		}
 	case database.RedisRequest:
-		response, err = sendRedis(a.ctx, request)
+		response, err = a.sendRedis(database.RequestID(requestID), request)
 		if err != nil {
 			return nil, errors.Wrapf(err, "send redis request id=%q", requestID)
 		}
@@ -0,0 +1,398 @@
+package app
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// jqNormalize converts decoded value to json values gojq works with: maps with string keys,
+// json.Number numbers and strings for times. NOTE: numbers are not float64, so big integers are kept.
+func jqNormalize(v any) (any, error) {
+	var stringKeys func(v any) any
+	stringKeys = func(v any) any {
+		switch v := v.(type) {
+		case map[any]any:
+			m := make(map[string]any, len(v))
+			for k, v := range v {
+				m[fmt.Sprint(k)] = stringKeys(v)
+			}
+			return m
+		case map[string]any:
+			for k, vv := range v {
+				v[k] = stringKeys(vv)
+			}
+			return v
+		case []any:
+			for i, vv := range v {
+				v[i] = stringKeys(vv)
+			}
+			return v
+		default:
+			return v
+		}
+	}
+
+	b, err := json.Marshal(stringKeys(v))
+	if err != nil {
+		return nil, errors.Wrap(err, "convert to json")
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var res any
+	if err := d.Decode(&res); err != nil {
+		return nil, errors.Wrap(err, "convert to json")
+	}
+	return res, nil
+}
+
+func jqDecodeYAML(data string) ([]any, error) {
+	d := yaml.NewDecoder(strings.NewReader(data))
+
+	inputs := []any{}
+	for {
+		var doc any
+		if err := d.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, errors.Wrapf(err, "document %d", len(inputs)+1)
+		}
+
+		v, err := jqNormalize(doc)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, v)
+	}
+	return inputs, nil
+}
+
+func jqDecodeTOML(data string) ([]any, error) {
+	var doc map[string]any
+	if _, err := toml.Decode(data, &doc); err != nil {
+		return nil, err
+	}
+
+	v, err := jqNormalize(doc)
+	if err != nil {
+		return nil, err
+	}
+	return []any{v}, nil
+}
+
+func jqDecodeCSV(data string) ([]any, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.FieldsPerRecord = -1 // NOTE: missing trailing fields are allowed
+
+	header, err := r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return []any{[]any{}}, nil
+		}
+		return nil, errors.Wrap(err, "read header")
+	}
+
+	rows := []any{}
+	for {
+		record, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, errors.Wrapf(err, "read row %d", len(rows)+1)
+		}
+
+		row := make(map[string]any, len(header))
+		for i, column := range header {
+			row[column] = nil
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return []any{rows}, nil
+}
+
+// xmlElement is element being decoded, see JQInputXML for resulting value
+type xmlElement struct {
+	name   string
+	fields map[string]any
+	text   strings.Builder
+}
+
+func (e *xmlElement) add(name string, value any) {
+	prev, ok := e.fields[name]
+	if !ok {
+		e.fields[name] = value
+		return
+	}
+
+	// NOTE: repeated elements become array, element values are never arrays themselves
+	if items, ok := prev.([]any); ok {
+		e.fields[name] = append(items, value)
+		return
+	}
+	e.fields[name] = []any{prev, value}
+}
+
+func (e *xmlElement) value() any {
+	text := strings.TrimSpace(e.text.String())
+	if len(e.fields) == 0 {
+		if text == "" {
+			return nil
+		}
+		return text
+	}
+
+	if text != "" {
+		e.fields["#text"] = text
+	}
+	return e.fields
+}
+
+func jqDecodeXML(data string) ([]any, error) {
+	d := xml.NewDecoder(strings.NewReader(data))
+
+	root := &xmlElement{fields: map[string]any{}}
+	stack := []*xmlElement{root}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		top := stack[len(stack)-1]
+		switch token := token.(type) {
+		case xml.StartElement:
+			e := &xmlElement{name: token.Name.Local, fields: map[string]any{}}
+			for _, attr := range token.Attr {
+				e.add("@"+attr.Name.Local, attr.Value)
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			stack[len(stack)-1].add(top.name, top.value())
+		case xml.CharData:
+			top.text.Write(token)
+		}
+	}
+	if len(stack) != 1 {
+		return nil, errors.New("unexpected end of document")
+	}
+	if len(root.fields) == 0 {
+		return nil, errors.New("no root element")
+	}
+	return []any{root.fields}, nil
+}
+
+// jqParse converts input data of given format to json values
+func jqParse(format database.JQInputFormat, data string) ([]any, error) {
+	var inputs []any
+	var err error
+	switch format {
+	case database.JQInputJSON, "":
+		inputs, err = jqDecode(data)
+	case database.JQInputYAML:
+		inputs, err = jqDecodeYAML(data)
+	case database.JQInputTOML:
+		inputs, err = jqDecodeTOML(data)
+	case database.JQInputCSV:
+		inputs, err = jqDecodeCSV(data)
+	case database.JQInputXML:
+		inputs, err = jqDecodeXML(data)
+	default:
+		return nil, errors.Errorf("unknown input format %q", format)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s", format)
+	}
+	return inputs, nil
+}
+
+func jqEncodeJSON(v any, indent bool) (string, error) {
+	var sb strings.Builder
+	e := json.NewEncoder(&sb)
+	if indent {
+		e.SetIndent("", "  ")
+	}
+	if err := e.Encode(v); err != nil {
+		return "", errors.Wrap(err, "encode result")
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// jqYAMLValue replaces big integers with yaml nodes, otherwise they are encoded as strings
+func jqYAMLValue(v any) any {
+	switch v := v.(type) {
+	case *big.Int:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: v.String()}
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, vv := range v {
+			m[k] = jqYAMLValue(vv)
+		}
+		return m
+	case []any:
+		items := make([]any, len(v))
+		for i, vv := range v {
+			items[i] = jqYAMLValue(vv)
+		}
+		return items
+	default:
+		return v
+	}
+}
+
+func jqEncodeYAML(v any) (string, error) {
+	var sb strings.Builder
+	e := yaml.NewEncoder(&sb)
+	e.SetIndent(2)
+	if err := e.Encode(jqYAMLValue(v)); err != nil {
+		return "", errors.Wrap(err, "encode result")
+	}
+	if err := e.Close(); err != nil {
+		return "", errors.Wrap(err, "encode result")
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// jqCSVCell formats value of table cell, nested values are written as json
+func jqCSVCell(v any) (string, error) {
+	switch v.(type) {
+	case map[string]any, []any:
+		return jqEncodeJSON(v, false)
+	default:
+		return exportString(v), nil
+	}
+}
+
+// jqEncodeCSV writes results as single table. Result which is array of objects or arrays gives row
+// for each element, other results are rows themselves. Header is union of object keys.
+func jqEncodeCSV(results []any) (string, error) {
+	var rows []any
+	for _, result := range results {
+		items, ok := result.([]any)
+		if ok && len(items) > 0 && !slices.ContainsFunc(items, func(item any) bool {
+			switch item.(type) {
+			case map[string]any, []any:
+				return false
+			default:
+				return true
+			}
+		}) {
+			rows = append(rows, items...)
+		} else {
+			rows = append(rows, result)
+		}
+	}
+
+	var header []string
+	for _, row := range rows {
+		if row, ok := row.(map[string]any); ok {
+			keys := make([]string, 0, len(row))
+			for k := range row {
+				if !slices.Contains(header, k) {
+					keys = append(keys, k)
+				}
+			}
+			slices.Sort(keys) // NOTE: gojq objects are unordered
+			header = append(header, keys...)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if len(header) > 0 {
+		if err := w.Write(header); err != nil {
+			return "", errors.Wrap(err, "write header")
+		}
+	}
+	for _, row := range rows {
+		var values []any
+		switch row := row.(type) {
+		case map[string]any:
+			values = make([]any, len(header))
+			for i, k := range header {
+				values[i] = row[k]
+			}
+		case []any:
+			values = row
+		default:
+			values = []any{row}
+		}
+
+		record := make([]string, len(values))
+		for i, v := range values {
+			cell, err := jqCSVCell(v)
+			if err != nil {
+				return "", err
+			}
+			record[i] = cell
+		}
+		if err := w.Write(record); err != nil {
+			return "", errors.Wrap(err, "write row")
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", errors.Wrap(err, "write csv")
+	}
+	return buf.String(), nil
+}
+
+// jqFormat formats query results in given format, csv results are joined into single table
+func jqFormat(format database.JQOutputFormat, results []any) ([]string, error) {
+	if format == database.JQOutputCSV {
+		table, err := jqEncodeCSV(results)
+		if err != nil {
+			return nil, err
+		}
+		return []string{table}, nil
+	}
+
+	res := make([]string, len(results))
+	for i, v := range results {
+		var s string
+		var err error
+		switch format {
+		case database.JQOutputJSON, "":
+			s, err = jqEncodeJSON(v, true)
+		case database.JQOutputCompactJSON:
+			s, err = jqEncodeJSON(v, false)
+		case database.JQOutputRaw:
+			if str, ok := v.(string); ok {
+				s = str
+			} else {
+				s, err = jqEncodeJSON(v, false)
+			}
+		case database.JQOutputYAML:
+			s, err = jqEncodeYAML(v)
+		default:
+			return nil, errors.Errorf("unknown output format %q", format)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "format result as %s", format)
+		}
+		res[i] = s
+	}
+	return res, nil
+}
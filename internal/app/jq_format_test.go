@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/itchyny/gojq"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+func TestJQParse(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format database.JQInputFormat
+		data   string
+		want   string // NOTE: inputs as compact json, one per line
+	}{
+		{"json stream", database.JQInputJSON, `{"a": 1} [2, 3.5]`, `{"a":1}` + "\n" + `[2,3.5]`},
+		{"json big integer", database.JQInputJSON, `9007199254740993`, `9007199254740993`},
+		{"yaml", database.JQInputYAML, "a: 1\nb: [x, true, null]", `{"a":1,"b":["x",true,null]}`},
+		{"yaml documents", database.JQInputYAML, "a: 1\n---\n- 2\n", `{"a":1}` + "\n" + `[2]`},
+		{"yaml non string keys", database.JQInputYAML, "1: a\ntrue: b", `{"1":"a","true":"b"}`},
+		{"yaml big integer", database.JQInputYAML, "n: 9007199254740993", `{"n":9007199254740993}`},
+		{"yaml float", database.JQInputYAML, "n: 1.5", `{"n":1.5}`},
+		{"toml", database.JQInputTOML, "a = 1\n[b]\nc = \"x\"", `{"a":1,"b":{"c":"x"}}`},
+		{"toml big integer", database.JQInputTOML, "n = 9007199254740993", `{"n":9007199254740993}`},
+		{"csv", database.JQInputCSV, "a,b\n1,x\n2,y\n", `[{"a":"1","b":"x"},{"a":"2","b":"y"}]`},
+		{"csv missing fields", database.JQInputCSV, "a,b\n1\n", `[{"a":"1","b":null}]`},
+		{"csv only header", database.JQInputCSV, "a,b\n", `[]`},
+		{"csv empty", database.JQInputCSV, "", `[]`},
+		{"xml", database.JQInputXML, `<a><b>x</b><c/></a>`, `{"a":{"b":"x","c":null}}`},
+		{"xml attributes", database.JQInputXML, `<a id="1">x</a>`, `{"a":{"#text":"x","@id":"1"}}`},
+		{"xml repeated elements", database.JQInputXML, `<a><b>1</b><b>2</b><b>3</b></a>`, `{"a":{"b":["1","2","3"]}}`},
+		{"xml nested repeated elements", database.JQInputXML, `<a><b><c>1</c></b><b><c>2</c></b></a>`, `{"a":{"b":[{"c":"1"},{"c":"2"}]}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inputs, err := jqParse(tc.format, tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(inputs))
+			for i, input := range inputs {
+				if got[i], err = jqEncodeJSON(input, false); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := strings.Join(got, "\n"); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJQParseError(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format database.JQInputFormat
+		data   string
+	}{
+		{"json", database.JQInputJSON, `{"a":`},
+		{"yaml", database.JQInputYAML, "a: [1"},
+		{"toml", database.JQInputTOML, "a = "},
+		{"csv", database.JQInputCSV, "a\n\"x"},
+		{"xml unclosed", database.JQInputXML, "<a><b></b>"},
+		{"xml no root", database.JQInputXML, "  "},
+		{"unknown format", "ini", "a=1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := jqParse(tc.format, tc.data); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestJQBigInteger(t *testing.T) {
+	q, err := gojq.Parse(".n + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := gojq.Compile(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		format database.JQInputFormat
+		data   string
+	}{
+		{"json", database.JQInputJSON, `{"n": 9007199254740993}`},
+		{"yaml", database.JQInputYAML, "n: 9007199254740993"},
+		{"toml", database.JQInputTOML, "n = 9007199254740993"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inputs, err := jqParse(tc.format, tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			results, err := jq(context.Background(), code, inputs[0], nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, format := range []database.JQOutputFormat{
+				database.JQOutputJSON,
+				database.JQOutputYAML,
+				database.JQOutputCSV,
+			} {
+				got, err := jqFormat(format, results)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := "9007199254740994"; strings.TrimSpace(got[0]) != want {
+					t.Errorf("%s: got %q, want %q", format, got[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestJQEncodeCSV(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		results []any
+		want    string
+	}{
+		{"objects", []any{[]any{
+			map[string]any{"b": 1, "a": "x"},
+			map[string]any{"a": "y", "c": nil},
+		}}, "a,b,c\nx,1,\ny,,\n"},
+		{"arrays", []any{[]any{[]any{1, "x"}, []any{2, "y"}}}, "1,x\n2,y\n"},
+		{"scalars", []any{1, "x", nil}, "1\nx\n\n"},
+		{"array of scalars is row", []any{[]any{1, 2}}, "1,2\n"},
+		{"nested values", []any{map[string]any{"a": []any{1}, "b": map[string]any{"c": 2}}}, "a,b\n[1],\"{\"\"c\"\":2}\"\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := jqEncodeCSV(tc.results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
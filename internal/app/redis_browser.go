@@ -0,0 +1,325 @@
+package app
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type redisKeyInfo struct {
+	Key string `json:"key"`
+	// Encoding is "base64" for binary keys
+	Encoding string `json:"encoding"`
+	Type     string `json:"type"`
+	// TTL is time to live in milliseconds, -1 if key has no expiration
+	TTL int64 `json:"ttl"`
+	// MemoryUsage is size in bytes, -1 if server does not report it
+	MemoryUsage int64 `json:"memory_usage"`
+}
+
+type RedisScanPage struct {
+	// Cursor is cursor of next page, "0" if iteration is done
+	Cursor string         `json:"cursor"`
+	Keys   []redisKeyInfo `json:"keys"`
+}
+
+type RedisKeyPage struct {
+	Type string `json:"type"`
+	// Total is length of value: string length, number of fields, elements or entries
+	Total int64 `json:"total"`
+	// Cursor is cursor of next page, "0" if value is fetched completely
+	Cursor string `json:"cursor"`
+	// Value is string for strings, map for hashes, array for lists, set for sets,
+	// map from member to score for sorted sets and map from id to fields for streams
+	Value respValue `json:"value"`
+}
+
+func respInt(v respValue) int64 {
+	switch v := v.Value.(type) {
+	case int64:
+		return v
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	default:
+		return 0
+	}
+}
+
+// respPairs converts flat list of keys and values to map
+func respPairs(items []respValue) respValue {
+	entries := make([]respEntry, 0, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		entries = append(entries, respEntry{items[i], items[i+1]})
+	}
+	return respValue{Type: respMap, Entries: entries}
+}
+
+func redisKeyName(key, encoding string) (string, error) {
+	if encoding != respEncodingBytes {
+		return key, nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", errors.Wrap(err, "decode key")
+	}
+	return string(b), nil
+}
+
+// redisCheck returns error if any reply is error
+func redisCheck(replies []respReply) error {
+	for _, reply := range replies {
+		if reply.Value.Type == respError {
+			return errors.New(reply.Value.Str())
+		}
+	}
+	return nil
+}
+
+func redisScan(conn *respConn, pattern, cursor string, count int) (RedisScanPage, error) {
+	reply, err := conn.do("SCAN", cursor, "MATCH", pattern, "COUNT", strconv.Itoa(count))
+	if err != nil {
+		return RedisScanPage{}, errors.Wrap(err, "SCAN")
+	}
+	if len(reply.Items) != 2 {
+		return RedisScanPage{}, errors.Errorf("unexpected SCAN reply of %d items", len(reply.Items))
+	}
+
+	keys := reply.Items[1].Items
+	commands := make([][]string, 0, 3*len(keys))
+	values := make([]string, len(keys)) // NOTE: base64 for binary keys
+	for i, key := range keys {
+		value, ok := key.Value.(string)
+		if !ok {
+			return RedisScanPage{}, errors.Errorf("unexpected SCAN key of type %s", key.Type)
+		}
+		values[i] = value
+
+		name := key.Str()
+		commands = append(commands,
+			[]string{"TYPE", name},
+			[]string{"PTTL", name},
+			[]string{"MEMORY", "USAGE", name},
+		)
+	}
+	replies, err := conn.pipeline(commands)
+	if err != nil {
+		return RedisScanPage{}, errors.Wrap(err, "get keys info")
+	}
+
+	res := RedisScanPage{
+		Cursor: reply.Items[0].Str(),
+		Keys:   make([]redisKeyInfo, len(keys)),
+	}
+	for i, key := range keys {
+		typ, ttl, memory := replies[3*i].Value, replies[3*i+1].Value, replies[3*i+2].Value
+		info := redisKeyInfo{
+			Key:         values[i],
+			Encoding:    key.Encoding,
+			Type:        typ.Str(),
+			TTL:         respInt(ttl),
+			MemoryUsage: -1,
+		}
+		if memory.Type == respInteger {
+			info.MemoryUsage = respInt(memory) // NOTE: MEMORY USAGE may be disabled or missing on old servers
+		}
+		res.Keys[i] = info
+	}
+	return res, nil
+}
+
+// RedisScan iterates keys matching glob pattern with SCAN, starting from cursor "0",
+// and returns type, ttl and memory usage of every key. Cluster nodes are scanned one by one.
+func (a *App) RedisScan(requestID, pattern, cursor string, count int) (RedisScanPage, error) {
+	req, err := a.getRedisRequest(requestID)
+	if err != nil {
+		return RedisScanPage{}, err
+	}
+
+	topology, err := a.redisTopology(req)
+	if err != nil {
+		return RedisScanPage{}, err
+	}
+
+	masters, err := topology.masters(a.ctx)
+	if err != nil {
+		return RedisScanPage{}, err
+	}
+
+	if cursor == "" {
+		cursor = "0"
+	}
+	if pattern == "" {
+		pattern = "*"
+	}
+	if count <= 0 {
+		count = 100
+	}
+
+	// NOTE: cursor of cluster node other than first is prefixed with node index like "1:123"
+	node := 0
+	if prefix, nodeCursor, ok := strings.Cut(cursor, ":"); ok {
+		if node, err = strconv.Atoi(prefix); err != nil || node < 0 || node >= len(masters) {
+			return RedisScanPage{}, errors.Errorf("invalid cursor %q", cursor)
+		}
+		cursor = nodeCursor
+	}
+
+	var res RedisScanPage
+	if err := a.redisDo(topology.ssh, masters[node], func(conn *respConn) error {
+		res, err = redisScan(conn, pattern, cursor, count)
+		return err
+	}); err != nil {
+		return RedisScanPage{}, err
+	}
+
+	switch {
+	case res.Cursor == "0" && node+1 < len(masters):
+		res.Cursor = strconv.Itoa(node+1) + ":0"
+	case res.Cursor != "0" && node > 0:
+		res.Cursor = strconv.Itoa(node) + ":" + res.Cursor
+	}
+	return res, nil
+}
+
+// RedisKeyValue fetches page of key value with command appropriate for key type.
+// Cursor is "0" for first page, count is page size, strings are fetched at once.
+func (a *App) RedisKeyValue(requestID, key, encoding, cursor string, count int) (RedisKeyPage, error) {
+	req, err := a.getRedisRequest(requestID)
+	if err != nil {
+		return RedisKeyPage{}, err
+	}
+
+	name, err := redisKeyName(key, encoding)
+	if err != nil {
+		return RedisKeyPage{}, err
+	}
+
+	topology, err := a.redisTopology(req)
+	if err != nil {
+		return RedisKeyPage{}, err
+	}
+
+	node, err := topology.nodeOf(a.ctx, name)
+	if err != nil {
+		return RedisKeyPage{}, err
+	}
+
+	if cursor == "" {
+		cursor = "0"
+	}
+	if count <= 0 {
+		count = 100
+	}
+
+	var res RedisKeyPage
+	if err := a.redisDo(topology.ssh, node, func(conn *respConn) error {
+		res, err = redisKeyPage(conn, name, cursor, count)
+		return err
+	}); err != nil {
+		return RedisKeyPage{}, err
+	}
+	return res, nil
+}
+
+func redisKeyPage(conn *respConn, name, cursor string, count int) (RedisKeyPage, error) {
+	typ, err := conn.do("TYPE", name)
+	if err != nil {
+		return RedisKeyPage{}, errors.Wrap(err, "TYPE")
+	}
+
+	// NOTE: offset based cursor for commands taking index ranges
+	offset, _ := strconv.Atoi(cursor)
+	nextOffset := func(total int64) string {
+		if int64(offset+count) >= total {
+			return "0"
+		}
+		return strconv.Itoa(offset + count)
+	}
+	stop := strconv.Itoa(offset + count - 1)
+
+	var length, fetch []string
+	switch typ.Str() {
+	case "none":
+		return RedisKeyPage{}, errors.Errorf("key %q does not exist", name)
+	case "string":
+		length, fetch = []string{"STRLEN", name}, []string{"GET", name}
+	case "hash":
+		length, fetch = []string{"HLEN", name}, []string{"HSCAN", name, cursor, "COUNT", strconv.Itoa(count)}
+	case "set":
+		length, fetch = []string{"SCARD", name}, []string{"SSCAN", name, cursor, "COUNT", strconv.Itoa(count)}
+	case "list":
+		length, fetch = []string{"LLEN", name}, []string{"LRANGE", name, strconv.Itoa(offset), stop}
+	case "zset":
+		length, fetch = []string{"ZCARD", name}, []string{"ZRANGE", name, strconv.Itoa(offset), stop, "WITHSCORES"}
+	case "stream":
+		start := "-"
+		if cursor != "0" {
+			start = cursor
+		}
+		length, fetch = []string{"XLEN", name}, []string{"XRANGE", name, start, "+", "COUNT", strconv.Itoa(count)}
+	default:
+		return RedisKeyPage{}, errors.Errorf("values of type %s are not supported", typ.Str())
+	}
+
+	replies, err := conn.pipeline([][]string{length, fetch})
+	if err != nil {
+		return RedisKeyPage{}, errors.Wrap(err, "fetch value")
+	}
+	if err := redisCheck(replies); err != nil {
+		return RedisKeyPage{}, errors.Wrap(err, "fetch value")
+	}
+
+	res := RedisKeyPage{
+		Type:   typ.Str(),
+		Total:  respInt(replies[0].Value),
+		Cursor: "0",
+	}
+	value := replies[1].Value
+	switch res.Type {
+	case "string":
+		res.Value = value
+	case "hash", "set":
+		if len(value.Items) != 2 {
+			return RedisKeyPage{}, errors.Errorf("unexpected %s reply of %d items", fetch[0], len(value.Items))
+		}
+
+		res.Cursor = value.Items[0].Str()
+		if res.Type == "hash" {
+			res.Value = respPairs(value.Items[1].Items)
+		} else {
+			res.Value = respValue{Type: respSet, Items: value.Items[1].Items}
+		}
+	case "list":
+		res.Cursor = nextOffset(res.Total)
+		res.Value = value
+	case "zset":
+		res.Cursor = nextOffset(res.Total)
+		// NOTE: RESP3 replies with [member, score] pairs, RESP2 with flat list
+		items := value.Items
+		if len(items) > 0 && items[0].Type == respArray {
+			flat := make([]respValue, 0, 2*len(items))
+			for _, pair := range items {
+				flat = append(flat, pair.Items...)
+			}
+			items = flat
+		}
+		res.Value = respPairs(items)
+	case "stream":
+		entries := make([]respEntry, len(value.Items))
+		for i, entry := range value.Items {
+			if len(entry.Items) != 2 {
+				return RedisKeyPage{}, errors.Errorf("unexpected XRANGE entry of %d items", len(entry.Items))
+			}
+			entries[i] = respEntry{entry.Items[0], respPairs(entry.Items[1].Items)}
+		}
+		if len(entries) == count {
+			res.Cursor = "(" + entries[len(entries)-1].Key.Str() // NOTE: exclusive range start
+		}
+		res.Value = respValue{Type: respMap, Entries: entries}
+	}
+	return res, nil
+}
@@ -0,0 +1,486 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// _redisIdleCheck is idle time after which cached connection is checked with PING before use
+const _redisIdleCheck = time.Minute
+
+// redisNode is server to connect to
+type redisNode struct {
+	Addr string
+	opts respOptions
+}
+
+// redisTopology is cached deployment of request: single master for standalone and sentinel,
+// masters serving key slots for cluster
+type redisTopology struct {
+	ssh  *database.SSHTunnel
+	opts respOptions // NOTE: options of data nodes
+
+	master string // NOTE: standalone and sentinel
+
+	cluster *redis.ClusterClient
+	mu      sync.Mutex                    // NOTE: guards cluster masters and commands, fetched on first use
+	primary []redisNode                   // NOTE: dropped on MOVED
+	command map[string]*redis.CommandInfo // NOTE: by lowercase name
+}
+
+type redisCachedConn struct {
+	conn *respConn
+	used time.Time
+}
+
+// redisURL adds scheme to bare host:port
+func redisURL(dsn string) string {
+	if !strings.Contains(dsn, "://") {
+		return "redis://" + dsn
+	}
+	return dsn
+}
+
+func (t *redisTopology) node(addr string) redisNode {
+	return redisNode{addr, t.opts}
+}
+
+// masters returns primary nodes, sorted by address
+func (t *redisTopology) masters(ctx context.Context) ([]redisNode, error) {
+	if t.cluster == nil {
+		return []redisNode{t.node(t.master)}, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.primary != nil {
+		return t.primary, nil
+	}
+
+	var mu sync.Mutex
+	var masters []redisNode
+	if err := t.cluster.ForEachMaster(ctx, func(_ context.Context, client *redis.Client) error {
+		mu.Lock()
+		defer mu.Unlock()
+		masters = append(masters, t.node(client.Options().Addr))
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "get cluster masters")
+	}
+	slices.SortFunc(masters, func(a, b redisNode) int {
+		return strings.Compare(a.Addr, b.Addr)
+	})
+	t.primary = masters
+	return masters, nil
+}
+
+// nodeOf returns node serving key
+func (t *redisTopology) nodeOf(ctx context.Context, key string) (redisNode, error) {
+	if t.cluster == nil {
+		return t.node(t.master), nil
+	}
+
+	client, err := t.cluster.MasterForKey(ctx, key)
+	if err != nil {
+		return redisNode{}, errors.Wrapf(err, "get node of key %q", key)
+	}
+	return t.node(client.Options().Addr), nil
+}
+
+// redirect returns node from MOVED or ASK error and whether it is ASK.
+// Slots map of cluster is reloaded on MOVED.
+func (t *redisTopology) redirect(ctx context.Context, reply respValue) (redisNode, bool, bool) {
+	if t.cluster == nil || reply.Type != respError {
+		return redisNode{}, false, false
+	}
+
+	fields := strings.Fields(reply.Str())
+	if len(fields) != 3 || fields[0] != "MOVED" && fields[0] != "ASK" {
+		return redisNode{}, false, false
+	}
+	if fields[0] == "MOVED" {
+		t.cluster.ReloadState(ctx)
+		t.mu.Lock()
+		t.primary = nil
+		t.mu.Unlock()
+	}
+	return t.node(fields[2]), fields[0] == "ASK", true
+}
+
+// commandKeys returns keys of command, cluster nodes are asked only about commands with keys in variable positions
+func (a *App) commandKeys(t *redisTopology, command []string) ([]string, error) {
+	t.mu.Lock()
+	if t.command == nil {
+		commands, err := t.cluster.Command(a.ctx).Result()
+		if err != nil {
+			t.mu.Unlock()
+			return nil, errors.Wrap(err, "get commands info")
+		}
+		t.command = commands
+	}
+	info, ok := t.command[strings.ToLower(command[0])]
+	t.mu.Unlock()
+
+	switch {
+	case !ok || info.FirstKeyPos <= 0:
+		return nil, nil // NOTE: unknown commands fail on any node
+	case slices.Contains(info.Flags, "movablekeys"):
+		masters, err := t.masters(a.ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var reply respValue
+		if err := a.redisDo(t.ssh, masters[0], func(conn *respConn) error {
+			var err error
+			reply, err = conn.do(append([]string{"COMMAND", "GETKEYS"}, command...)...)
+			return err
+		}); err != nil {
+			return nil, nil //nolint:nilerr // NOTE: command without keys, like EVAL with no keys
+		}
+
+		keys := make([]string, len(reply.Items))
+		for i, key := range reply.Items {
+			keys[i] = key.Str()
+		}
+		return keys, nil
+	}
+
+	last := int(info.LastKeyPos)
+	if last < 0 {
+		last += len(command)
+	}
+	step := max(int(info.StepCount), 1)
+	var keys []string
+	for i := int(info.FirstKeyPos); i <= last && i < len(command); i += step {
+		keys = append(keys, command[i])
+	}
+	return keys, nil
+}
+
+func (a *App) redisDial(ssh *database.SSHTunnel, node redisNode) (*respConn, error) {
+	dial := a.redisDialer(ssh, node.opts.TLS, node.opts.DialTimeout)
+	conn, err := dialRESP(a.ctx, node.Addr, node.opts, dial)
+	if err != nil {
+		return nil, errors.Wrapf(err, "connect to %s", node.Addr)
+	}
+	return conn, nil
+}
+
+func redisConnKey(ssh *database.SSHTunnel, node redisNode) string {
+	opts := node.opts
+	var sshKey string
+	if ssh != nil {
+		sshKey = strings.Join([]string{ssh.Host, ssh.User, ssh.KeyFile, string(ssh.KnownHosts)}, " ")
+	}
+	return fmt.Sprint(
+		node.Addr, sshKey, opts.Username, opts.Password, opts.DB, opts.Protocol, opts.ClientName,
+		opts.TLS != nil, opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout,
+	)
+}
+
+// redisConn returns locked cached connection to node, caller must call redisRelease
+func (a *App) redisConn(ssh *database.SSHTunnel, node redisNode) (*respConn, error) {
+	key := redisConnKey(ssh, node)
+	for {
+		a.redisConnsMu.Lock()
+		cached, ok := a.redisConns[key]
+		a.redisConnsMu.Unlock()
+
+		if !ok {
+			conn, err := a.redisDial(ssh, node)
+			if err != nil {
+				return nil, err
+			}
+			conn.mu.Lock()
+
+			a.redisConnsMu.Lock()
+			if _, ok := a.redisConns[key]; ok {
+				a.redisConnsMu.Unlock()
+				conn.mu.Unlock()
+				conn.Close() // NOTE: other call has connected meanwhile
+				continue
+			}
+			a.redisConns[key] = &redisCachedConn{conn, time.Now()}
+			a.redisConnsMu.Unlock()
+			return conn, nil
+		}
+
+		conn := cached.conn
+		conn.mu.Lock()
+		if !conn.broken && time.Since(cached.used) > _redisIdleCheck {
+			_, _ = conn.do("PING") // NOTE: server may have closed idle connection
+		}
+		if conn.broken {
+			a.redisRelease(key, conn)
+			continue
+		}
+		return conn, nil
+	}
+}
+
+// redisRelease unlocks connection, broken connections are closed and removed from cache
+func (a *App) redisRelease(key string, conn *respConn) {
+	a.redisConnsMu.Lock()
+	defer a.redisConnsMu.Unlock()
+	defer conn.mu.Unlock()
+
+	cached, ok := a.redisConns[key]
+	ok = ok && cached.conn == conn
+	switch {
+	case !conn.broken && ok:
+		cached.used = time.Now()
+	case conn.broken:
+		if ok {
+			delete(a.redisConns, key)
+		}
+		conn.Close()
+	}
+}
+
+// redisDo runs f on cached connection to node
+func (a *App) redisDo(ssh *database.SSHTunnel, node redisNode, f func(*respConn) error) error {
+	conn, err := a.redisConn(ssh, node)
+	if err != nil {
+		return err
+	}
+	defer a.redisRelease(redisConnKey(ssh, node), conn)
+	return f(conn)
+}
+
+func (a *App) closeRedisConns() {
+	a.redisConnsMu.Lock()
+	defer a.redisConnsMu.Unlock()
+
+	for key, cached := range a.redisConns {
+		if err := cached.conn.Close(); err != nil {
+			log.Error().Err(err).Str("addr", cached.conn.conn.RemoteAddr().String()).Msg("close redis connection")
+		}
+		delete(a.redisConns, key)
+	}
+}
+
+// redisDialer returns dialer connecting through ssh tunnel if set and over tls if config is set.
+// It is used both by go-redis clients and by command connections, so they connect same way.
+func (a *App) redisDialer(ssh *database.SSHTunnel, tlsConfig *tls.Config, timeout time.Duration) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		local, err := a.tunnel(ssh, addr)
+		if err != nil {
+			return nil, errors.Wrap(err, "open ssh tunnel")
+		}
+
+		conn, err := (&net.Dialer{Timeout: max(timeout, 0)}).DialContext(ctx, network, local)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil {
+			return conn, nil
+		}
+
+		config := tlsConfig.Clone()
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(addr) // NOTE: tunneled address is local
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "tls handshake")
+		}
+		return tlsConn, nil
+	}
+}
+
+// redisSentinelMaster asks sentinels for address of master, first sentinel answered wins
+func (a *App) redisSentinelMaster(ssh *database.SSHTunnel, opts *redis.Options, sentinels []string, masterName string) (string, error) {
+	if masterName == "" {
+		return "", errors.New("master name is not set")
+	}
+
+	var errs []string
+	for _, sentinel := range sentinels {
+		// NOTE: sentinels are asked without credentials, which are for master
+		client := redis.NewSentinelClient(&redis.Options{
+			Addr:         sentinel,
+			Dialer:       a.redisDialer(ssh, opts.TLSConfig, opts.DialTimeout),
+			Protocol:     opts.Protocol,
+			TLSConfig:    opts.TLSConfig,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+			MaxRetries:   -1,
+		})
+		addr, err := client.GetMasterAddrByName(a.ctx, masterName).Result()
+		client.Close()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				err = errors.Errorf("unknown master %q", masterName)
+			}
+			errs = append(errs, fmt.Sprintf("sentinel %s: %s", sentinel, err))
+			continue
+		}
+		return net.JoinHostPort(addr[0], addr[1]), nil
+	}
+	return "", errors.Errorf("resolve master: %s", strings.Join(errs, "; "))
+}
+
+// go-redis defaults for timeouts not set in dsn
+const (
+	_redisDialTimeout = 5 * time.Second
+	_redisReadTimeout = 3 * time.Second
+)
+
+// respOptionsOf converts go-redis options, timeouts are defaulted like go-redis does:
+// zero is default and negative is no timeout, write timeout defaults to read timeout.
+func respOptionsOf(
+	username, password string,
+	db, protocol int,
+	clientName string,
+	tlsConfig *tls.Config,
+	dialTimeout, readTimeout, writeTimeout time.Duration,
+) respOptions {
+	if dialTimeout == 0 {
+		dialTimeout = _redisDialTimeout
+	}
+	if readTimeout == 0 {
+		readTimeout = _redisReadTimeout
+	}
+	if writeTimeout == 0 {
+		writeTimeout = readTimeout
+	}
+	return respOptions{
+		Username:     username,
+		Password:     password,
+		DB:           db,
+		Protocol:     protocol,
+		ClientName:   clientName,
+		TLS:          tlsConfig,
+		DialTimeout:  dialTimeout,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+	}
+}
+
+func redisTopologyKey(request database.RedisRequest) string {
+	var sshKey string
+	if request.SSH != nil {
+		sshKey = strings.Join([]string{request.SSH.Host, request.SSH.User, request.SSH.KeyFile, string(request.SSH.KnownHosts)}, " ")
+	}
+	return fmt.Sprint(sshKey, request.DSN, request.Deployment.Kind, request.Deployment.MasterName, request.Deployment.Nodes)
+}
+
+// redisTopology returns cached deployment of request, resolving it on first use
+func (a *App) redisTopology(request database.RedisRequest) (*redisTopology, error) {
+	key := redisTopologyKey(request)
+	a.redisTopologiesMu.Lock()
+	t, ok := a.redisTopologies[key]
+	a.redisTopologiesMu.Unlock()
+	if ok {
+		return t, nil
+	}
+
+	t, err := a.newRedisTopology(request)
+	if err != nil {
+		return nil, err
+	}
+
+	a.redisTopologiesMu.Lock()
+	defer a.redisTopologiesMu.Unlock()
+	if cached, ok := a.redisTopologies[key]; ok {
+		t.close() // NOTE: other call has resolved it meanwhile
+		return cached, nil
+	}
+	a.redisTopologies[key] = t
+	return t, nil
+}
+
+// forgetRedisTopology drops cached deployment, e.g. when sentinel master is unreachable after failover
+func (a *App) forgetRedisTopology(request database.RedisRequest, t *redisTopology) {
+	a.redisTopologiesMu.Lock()
+	defer a.redisTopologiesMu.Unlock()
+
+	key := redisTopologyKey(request)
+	if a.redisTopologies[key] == t {
+		delete(a.redisTopologies, key)
+		t.close()
+	}
+}
+
+func (t *redisTopology) close() {
+	if t.cluster != nil {
+		if err := t.cluster.Close(); err != nil {
+			log.Error().Err(err).Msg("close redis cluster client")
+		}
+	}
+}
+
+func (a *App) closeRedisTopologies() {
+	a.redisTopologiesMu.Lock()
+	defer a.redisTopologiesMu.Unlock()
+
+	for key, t := range a.redisTopologies {
+		t.close()
+		delete(a.redisTopologies, key)
+	}
+}
+
+func (a *App) newRedisTopology(request database.RedisRequest) (*redisTopology, error) {
+	t := &redisTopology{ssh: request.SSH}
+	switch request.Deployment.Kind {
+	case database.RedisDeploymentStandalone, "":
+		opts, err := redis.ParseURL(redisURL(request.DSN))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse dsn %q", request.DSN)
+		}
+
+		t.master = opts.Addr
+		t.opts = respOptionsOf(
+			opts.Username, opts.Password, opts.DB, opts.Protocol, opts.ClientName,
+			opts.TLSConfig, opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout,
+		)
+	case database.RedisDeploymentSentinel:
+		opts, err := redis.ParseURL(redisURL(request.DSN))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse dsn %q", request.DSN)
+		}
+
+		sentinels := append([]string{opts.Addr}, request.Deployment.Nodes...)
+		master, err := a.redisSentinelMaster(request.SSH, opts, sentinels, request.Deployment.MasterName)
+		if err != nil {
+			return nil, err
+		}
+
+		t.master = master
+		t.opts = respOptionsOf(
+			opts.Username, opts.Password, opts.DB, opts.Protocol, opts.ClientName,
+			opts.TLSConfig, opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout,
+		)
+	case database.RedisDeploymentCluster:
+		opts, err := redis.ParseClusterURL(redisURL(request.DSN))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse dsn %q", request.DSN)
+		}
+
+		opts.Addrs = append(opts.Addrs, request.Deployment.Nodes...)
+		opts.Dialer = a.redisDialer(request.SSH, opts.TLSConfig, opts.DialTimeout)
+		t.cluster = redis.NewClusterClient(opts)
+		t.opts = respOptionsOf(
+			opts.Username, opts.Password, 0, opts.Protocol, opts.ClientName,
+			opts.TLSConfig, opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout,
+		)
+	default:
+		return nil, errors.Errorf("unknown deployment kind %q", request.Deployment.Kind)
+	}
+	return t, nil
+}
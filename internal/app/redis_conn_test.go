@@ -0,0 +1,29 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRespOptionsOfTimeouts(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		dial, read, write  time.Duration
+		wantDial, wantRead time.Duration
+		wantWrite          time.Duration
+	}{
+		{"defaults", 0, 0, 0, 5 * time.Second, 3 * time.Second, 3 * time.Second},
+		{"write defaults to read", time.Second, 2 * time.Second, 0, time.Second, 2 * time.Second, 2 * time.Second},
+		{"set", time.Second, 2 * time.Second, 4 * time.Second, time.Second, 2 * time.Second, 4 * time.Second},
+		{"disabled", -1, -1, -1, -1, -1, -1},
+		{"read disabled", 0, -1, 0, 5 * time.Second, -1, -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := respOptionsOf("", "", 0, 0, "", nil, tc.dial, tc.read, tc.write)
+			if opts.DialTimeout != tc.wantDial || opts.ReadTimeout != tc.wantRead || opts.WriteTimeout != tc.wantWrite {
+				t.Fatalf("got dial %v, read %v, write %v, want %v, %v, %v",
+					opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout, tc.wantDial, tc.wantRead, tc.wantWrite)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package app
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// redisSubscription is connection listening to channels or streams until stopped
+type redisSubscription struct {
+	conn    *respConn
+	stopped atomic.Bool
+}
+
+func (s *redisSubscription) stop() error {
+	s.stopped.Store(true)
+	return s.conn.Close() // NOTE: interrupts blocked read
+}
+
+// redisMessage records received message and notifies frontend about it
+func (a *App) redisMessage(id database.RequestID, channel, pattern, entryID string, payload respValue) (database.RedisMessage, error) {
+	b, err := json.Marshal(payload.Plain())
+	if err != nil {
+		return database.RedisMessage{}, errors.Wrap(err, "marshal payload")
+	}
+
+	message := database.RedisMessage{channel, pattern, entryID, string(b), time.Now()}
+	runtime.EventsEmit(a.ctx, "redis:message:"+string(id), message)
+	return message, nil
+}
+
+func (a *App) listenPubSub(id database.RequestID, conn *respConn, request database.RedisRequest) ([]database.RedisMessage, error) {
+	cmd := "SUBSCRIBE"
+	if request.Mode == database.RedisModePSubscribe {
+		cmd = "PSUBSCRIBE"
+	}
+	conn.write(append([]string{cmd}, request.Subscription.Channels...))
+	if err := conn.flush(); err != nil {
+		return nil, errors.Wrap(err, cmd)
+	}
+	conn.conn.SetReadDeadline(time.Time{}) // NOTE: messages are awaited until stopped
+
+	var messages []database.RedisMessage
+	for {
+		reply, err := conn.read()
+		if err != nil {
+			return messages, err
+		}
+		if reply.Type == respError {
+			return messages, errors.Errorf("%s: %s", cmd, reply.Str())
+		}
+
+		// NOTE: RESP2 delivers messages as arrays, RESP3 as pushes
+		var channel, pattern string
+		var payload respValue
+		switch items := reply.Items; {
+		case len(items) == 3 && strings.EqualFold(items[0].Str(), "message"):
+			channel, payload = items[1].Str(), items[2]
+		case len(items) == 4 && strings.EqualFold(items[0].Str(), "pmessage"):
+			pattern, channel, payload = items[1].Str(), items[2].Str(), items[3]
+		default:
+			continue // NOTE: subscription confirmations
+		}
+
+		message, err := a.redisMessage(id, channel, pattern, "", payload)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, message)
+	}
+}
+
+// redisLastID returns id of last entry of stream, so that reading continues from it without missing entries
+func redisLastID(conn *respConn, key string) (string, error) {
+	reply, err := conn.do("XREVRANGE", key, "+", "-", "COUNT", "1")
+	if err != nil {
+		return "", errors.Wrapf(err, "get last id of stream %q", key)
+	}
+	if len(reply.Items) == 0 || len(reply.Items[0].Items) == 0 {
+		return "0-0", nil
+	}
+	return reply.Items[0].Items[0].Str(), nil
+}
+
+func (a *App) listenStreams(id database.RequestID, sub *redisSubscription, request database.RedisRequest) ([]database.RedisMessage, error) {
+	conn, keys, group := sub.conn, request.Subscription.Channels, request.Mode == database.RedisModeXReadGroup
+	if group && (request.Subscription.Group == "" || request.Subscription.Consumer == "") {
+		return nil, errors.New("group and consumer must be set for XREADGROUP")
+	}
+
+	start := request.Subscription.Start
+	if start == "" {
+		start = "$"
+		if group {
+			start = ">"
+		}
+	}
+
+	ids := map[string]string{}
+	for _, key := range keys {
+		ids[key] = start
+		if start == "$" {
+			lastID, err := redisLastID(conn, key)
+			if err != nil {
+				return nil, err
+			}
+			ids[key] = lastID
+		}
+	}
+
+	// NOTE: server must answer before read timeout
+	block := time.Second
+	if timeout := conn.opts.ReadTimeout; timeout > 0 && timeout <= 2*block {
+		block = timeout / 2
+	}
+	blockMillis := strconv.FormatInt(max(block.Milliseconds(), 1), 10)
+
+	var messages []database.RedisMessage
+	for !sub.stopped.Load() {
+		args := []string{"XREAD", "COUNT", "100", "BLOCK", blockMillis, "STREAMS"}
+		if group {
+			args = []string{
+				"XREADGROUP", "GROUP", request.Subscription.Group, request.Subscription.Consumer,
+				"COUNT", "100", "BLOCK", blockMillis, "STREAMS",
+			}
+		}
+		args = append(args, keys...)
+		for _, key := range keys {
+			args = append(args, ids[key])
+		}
+
+		reply, err := conn.do(args...)
+		if err != nil {
+			return messages, errors.Wrap(err, args[0])
+		}
+
+		// NOTE: RESP3 replies with map from stream to entries, RESP2 with list of [stream, entries] pairs
+		streams := reply.Entries
+		for _, item := range reply.Items {
+			if len(item.Items) == 2 {
+				streams = append(streams, respEntry{item.Items[0], item.Items[1]})
+			}
+		}
+
+		for _, stream := range streams {
+			key := stream.Key.Str()
+			if group && len(stream.Value.Items) == 0 && ids[key] != ">" {
+				ids[key] = ">" // NOTE: pending entries of group are read, continue with new ones
+			}
+
+			for _, entry := range stream.Value.Items {
+				if len(entry.Items) != 2 {
+					continue
+				}
+
+				entryID := entry.Items[0].Str()
+				if ids[key] != ">" {
+					ids[key] = entryID
+				}
+
+				message, err := a.redisMessage(id, key, "", entryID, respPairs(entry.Items[1].Items))
+				if err != nil {
+					return messages, err
+				}
+				messages = append(messages, message)
+			}
+		}
+	}
+	return messages, nil
+}
+
+// subscribeRedis listens to channels or streams until subscription is stopped, captured messages are returned
+func (a *App) subscribeRedis(id database.RequestID, request database.RedisRequest) (database.RedisResponse, error) {
+	if len(request.Subscription.Channels) == 0 {
+		return database.RedisResponse{}, errors.New("no channels given")
+	}
+
+	topology, err := a.redisTopology(request)
+	if err != nil {
+		return database.RedisResponse{}, err
+	}
+
+	// NOTE: pubsub messages are broadcast to all cluster nodes, streams are read from node serving them
+	var node redisNode
+	if request.Mode == database.RedisModeXRead || request.Mode == database.RedisModeXReadGroup {
+		node, err = topology.nodeOf(a.ctx, request.Subscription.Channels[0])
+	} else {
+		var masters []redisNode
+		masters, err = topology.masters(a.ctx)
+		if err == nil {
+			node = masters[0]
+		}
+	}
+	if err != nil {
+		return database.RedisResponse{}, err
+	}
+
+	conn, err := a.redisDial(topology.ssh, node) // NOTE: connection is not shared, it is blocked by listening
+	if err != nil {
+		return database.RedisResponse{}, err
+	}
+
+	sub := &redisSubscription{conn: conn}
+	a.redisSubscriptionsMu.Lock()
+	if _, ok := a.redisSubscriptions[id]; ok {
+		a.redisSubscriptionsMu.Unlock()
+		conn.Close()
+		return database.RedisResponse{}, errors.Errorf("subscription of request %q is already running", id)
+	}
+	a.redisSubscriptions[id] = sub
+	a.redisSubscriptionsMu.Unlock()
+	defer func() {
+		a.redisSubscriptionsMu.Lock()
+		delete(a.redisSubscriptions, id)
+		a.redisSubscriptionsMu.Unlock()
+		conn.Close()
+	}()
+
+	var messages []database.RedisMessage
+	switch request.Mode {
+	case database.RedisModeSubscribe, database.RedisModePSubscribe:
+		messages, err = a.listenPubSub(id, conn, request)
+	case database.RedisModeXRead, database.RedisModeXReadGroup:
+		messages, err = a.listenStreams(id, sub, request)
+	default:
+		return database.RedisResponse{}, errors.Errorf("unknown mode %q", request.Mode)
+	}
+	var listenErr string
+	if err != nil && !sub.stopped.Load() {
+		if len(messages) == 0 {
+			return database.RedisResponse{}, errors.Wrap(err, "listen")
+		}
+		listenErr = errors.Wrap(err, "listen").Error() // NOTE: captured messages are saved anyway
+	}
+
+	payloads := make([]json.RawMessage, len(messages))
+	for i, message := range messages {
+		payloads[i] = json.RawMessage(message.Payload)
+	}
+	response, err := json.Marshal(payloads)
+	if err != nil {
+		return database.RedisResponse{}, errors.Wrap(err, "marshal messages")
+	}
+
+	return database.RedisResponse{
+		Response: string(response),
+		Protocol: conn.proto,
+		Messages: messages,
+		Error:    listenErr,
+	}, nil
+}
+
+// RedisSubscriptionStop stops running subscription of request, captured messages are saved to history
+func (a *App) RedisSubscriptionStop(requestID string) error {
+	a.redisSubscriptionsMu.Lock()
+	defer a.redisSubscriptionsMu.Unlock()
+
+	sub, ok := a.redisSubscriptions[database.RequestID(requestID)]
+	if !ok {
+		return errors.Errorf("no running subscription for request %q", requestID)
+	}
+	if err := sub.stop(); err != nil {
+		return errors.Wrap(err, "close connection")
+	}
+	return nil
+}
+
+func (a *App) stopRedisSubscriptions() {
+	a.redisSubscriptionsMu.Lock()
+	defer a.redisSubscriptionsMu.Unlock()
+
+	for id, sub := range a.redisSubscriptions {
+		if err := sub.stop(); err != nil {
+			log.Error().Err(err).Str("request", string(id)).Msg("stop redis subscription")
+		}
+	}
+}
@@ -0,0 +1,510 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+type respType string
+
+const (
+	respSimpleString  respType = "simple_string"
+	respBulkString    respType = "bulk_string"
+	respVerbatim      respType = "verbatim_string"
+	respError         respType = "error"
+	respInteger       respType = "integer"
+	respDouble        respType = "double"
+	respBoolean       respType = "boolean"
+	respNull          respType = "null"
+	respBigNumber     respType = "big_number"
+	respArray         respType = "array"
+	respSet           respType = "set"
+	respMap           respType = "map"
+	respPush          respType = "push"
+	respEncodingBytes          = "base64"
+)
+
+// respValue is typed reply of redis server
+type respValue struct {
+	Type respType `json:"type"`
+	// Value is string, int64, float64 (or "inf", "-inf", "nan" string) or bool for scalar types
+	Value any `json:"value,omitempty"`
+	// Encoding is "base64" for binary strings, which are not valid utf8
+	Encoding string `json:"encoding,omitempty"`
+	// Format is verbatim string format like "txt" or "mkd"
+	Format  string      `json:"format,omitempty"`
+	Items   []respValue `json:"items,omitempty"`
+	Entries []respEntry `json:"entries,omitempty"`
+}
+
+type respEntry struct {
+	Key   respValue `json:"key"`
+	Value respValue `json:"value"`
+}
+
+func respString(typ respType, b []byte) respValue {
+	if !utf8.Valid(b) {
+		return respValue{Type: typ, Value: base64.StdEncoding.EncodeToString(b), Encoding: respEncodingBytes}
+	}
+	return respValue{Type: typ, Value: string(b)}
+}
+
+// Str returns string value, binary strings are decoded
+func (v respValue) Str() string {
+	s, _ := v.Value.(string)
+	if v.Encoding == respEncodingBytes {
+		b, _ := base64.StdEncoding.DecodeString(s)
+		return string(b)
+	}
+	return s
+}
+
+// Plain converts value to plain json value, maps with non string keys become lists of pairs
+func (v respValue) Plain() any {
+	switch v.Type {
+	case respNull:
+		return nil
+	case respError:
+		return map[string]any{"error": v.Value}
+	case respArray, respSet, respPush:
+		items := make([]any, len(v.Items))
+		for i, item := range v.Items {
+			items[i] = item.Plain()
+		}
+		return items
+	case respMap:
+		obj := make(orderedObject, 0, len(v.Entries))
+		for _, entry := range v.Entries {
+			switch entry.Key.Type {
+			case respSimpleString, respBulkString, respVerbatim:
+				obj = append(obj, orderedField{entry.Key.Str(), entry.Value.Plain()})
+			default:
+				pairs := make([]any, len(v.Entries))
+				for i, entry := range v.Entries {
+					pairs[i] = []any{entry.Key.Plain(), entry.Value.Plain()}
+				}
+				return pairs
+			}
+		}
+		return obj
+	default:
+		return v.Value
+	}
+}
+
+// respConn is connection to redis server talking RESP3 if server supports it, RESP2 otherwise.
+// NOTE: commands are not run with go-redis, since its replies are untyped: simple, bulk and verbatim strings
+// are all strings, sets and pushes are slices like arrays, maps are unordered and nulls are errors,
+// while query results show exact reply tree. go-redis is used for cluster and sentinel topology,
+// options come from its ParseURL and connections are dialed by redisDialer shared with go-redis clients,
+// so only RESP framing and handshake live here.
+type respConn struct {
+	mu     sync.Mutex // NOTE: guards shared connections
+	conn   net.Conn
+	r      *bufio.Reader
+	w      *bufio.Writer
+	opts   respOptions
+	proto  int
+	broken bool // NOTE: set on i/o and protocol errors, connection must not be reused
+	// block is longest wait of blocking commands written since flush, negative if some waits forever
+	block time.Duration
+}
+
+type respOptions struct {
+	Username string
+	Password string
+	DB       int
+	// Protocol is RESP version to use, 0 to negotiate
+	Protocol   int
+	ClientName string
+	// TLS is used to connect if set
+	TLS *tls.Config
+	// Timeouts are not applied if not positive, read timeout is extended by waits of blocking commands
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+func (c *respConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *respConn) write(args []string) {
+	if block := respBlockTimeout(args); block < 0 || c.block < 0 {
+		c.block = -1
+	} else {
+		c.block = max(c.block, block)
+	}
+
+	c.w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		c.w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		c.w.WriteString(arg)
+		c.w.WriteString("\r\n")
+	}
+}
+
+func (c *respConn) line() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.broken = true
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func (c *respConn) blob(size int) ([]byte, error) {
+	b := make([]byte, size+2)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		c.broken = true
+		return nil, err
+	}
+	return b[:size], nil
+}
+
+func (c *respConn) items(n int) ([]respValue, error) {
+	items := make([]respValue, n)
+	for i := range items {
+		item, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// read reads single reply
+func (c *respConn) read() (_ respValue, err error) {
+	defer func() {
+		if err != nil {
+			c.broken = true // NOTE: stream position is unknown after failed read
+		}
+	}()
+
+	line, err := c.line()
+	if err != nil {
+		return respValue{}, err
+	}
+	if line == "" {
+		return respValue{}, errors.New("empty reply line")
+	}
+
+	kind, rest := line[0], line[1:]
+	size := func() (int, error) {
+		if rest == "?" {
+			return 0, errors.New("streamed replies are not supported")
+		}
+		return strconv.Atoi(rest)
+	}
+
+	switch kind {
+	case '+':
+		return respString(respSimpleString, []byte(rest)), nil
+	case '-':
+		return respValue{Type: respError, Value: rest}, nil
+	case ':':
+		n, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			return respValue{}, errors.Wrap(err, "parse integer")
+		}
+		return respValue{Type: respInteger, Value: n}, nil
+	case ',':
+		switch rest {
+		case "inf", "-inf", "nan":
+			return respValue{Type: respDouble, Value: rest}, nil
+		}
+		f, err := strconv.ParseFloat(rest, 64)
+		if err != nil {
+			return respValue{}, errors.Wrap(err, "parse double")
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return respValue{Type: respDouble, Value: rest}, nil
+		}
+		return respValue{Type: respDouble, Value: f}, nil
+	case '#':
+		return respValue{Type: respBoolean, Value: rest == "t"}, nil
+	case '_':
+		return respValue{Type: respNull}, nil
+	case '(':
+		return respValue{Type: respBigNumber, Value: rest}, nil
+	case '$', '!', '=':
+		n, err := size()
+		if err != nil {
+			return respValue{}, err
+		}
+		if n < 0 {
+			return respValue{Type: respNull}, nil // NOTE: RESP2 null bulk string
+		}
+		b, err := c.blob(n)
+		if err != nil {
+			return respValue{}, err
+		}
+
+		switch kind {
+		case '!':
+			return respValue{Type: respError, Value: string(b)}, nil
+		case '=':
+			format, text, _ := strings.Cut(string(b), ":")
+			v := respString(respVerbatim, []byte(text))
+			v.Format = format
+			return v, nil
+		default:
+			return respString(respBulkString, b), nil
+		}
+	case '*', '~', '>':
+		n, err := size()
+		if err != nil {
+			return respValue{}, err
+		}
+		if n < 0 {
+			return respValue{Type: respNull}, nil // NOTE: RESP2 null array
+		}
+		items, err := c.items(n)
+		if err != nil {
+			return respValue{}, err
+		}
+		typ := map[byte]respType{'*': respArray, '~': respSet, '>': respPush}[kind]
+		return respValue{Type: typ, Items: items}, nil
+	case '%', '|':
+		n, err := size()
+		if err != nil {
+			return respValue{}, err
+		}
+		items, err := c.items(2 * n)
+		if err != nil {
+			return respValue{}, err
+		}
+		if kind == '|' {
+			return c.read() // NOTE: attributes are skipped, reply follows them
+		}
+
+		entries := make([]respEntry, n)
+		for i := range entries {
+			entries[i] = respEntry{items[2*i], items[2*i+1]}
+		}
+		return respValue{Type: respMap, Entries: entries}, nil
+	default:
+		return respValue{}, errors.Errorf("unknown reply type %q", kind)
+	}
+}
+
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// respBlockTimeout returns how long blocking command may wait for data, negative if it waits forever
+func respBlockTimeout(args []string) time.Duration {
+	if len(args) < 2 {
+		return 0
+	}
+
+	parse := func(s string, unit time.Duration) time.Duration {
+		f, err := strconv.ParseFloat(s, 64)
+		switch {
+		case err != nil || f < 0:
+			return 0
+		case f == 0:
+			return -1
+		default:
+			return time.Duration(f * float64(unit))
+		}
+	}
+	switch strings.ToUpper(args[0]) {
+	case "BLPOP", "BRPOP", "BRPOPLPUSH", "BLMOVE", "BZPOPMIN", "BZPOPMAX":
+		return parse(args[len(args)-1], time.Second)
+	case "BLMPOP", "BZMPOP":
+		return parse(args[1], time.Second)
+	case "WAIT", "WAITAOF":
+		return parse(args[len(args)-1], time.Millisecond)
+	case "XREAD", "XREADGROUP":
+		for i, arg := range args[:len(args)-1] {
+			switch strings.ToUpper(arg) {
+			case "BLOCK":
+				return parse(args[i+1], time.Millisecond)
+			case "STREAMS":
+				return 0
+			}
+		}
+	}
+	return 0
+}
+
+// flush sends written commands and sets read deadline for their replies
+func (c *respConn) flush() error {
+	block := c.block
+	c.block = 0
+
+	c.conn.SetWriteDeadline(deadline(c.opts.WriteTimeout))
+	if err := c.w.Flush(); err != nil {
+		c.broken = true
+		return err
+	}
+	timeout := c.opts.ReadTimeout + block
+	if block < 0 || c.opts.ReadTimeout <= 0 {
+		timeout = 0 // NOTE: commands waiting forever have no deadline
+	}
+	c.conn.SetReadDeadline(deadline(timeout))
+	return nil
+}
+
+// do sends command and reads its reply, error replies are returned as errors
+func (c *respConn) do(args ...string) (respValue, error) {
+	c.write(args)
+	if err := c.flush(); err != nil {
+		return respValue{}, err
+	}
+
+	reply, err := c.read()
+	if err != nil {
+		return respValue{}, err
+	}
+	if reply.Type == respError {
+		return respValue{}, errors.New(reply.Str())
+	}
+	return reply, nil
+}
+
+type respReply struct {
+	Value   respValue
+	Latency time.Duration
+}
+
+// pipeline sends all commands at once and reads their replies, latency of reply is time since commands were sent
+func (c *respConn) pipeline(commands [][]string) ([]respReply, error) {
+	for _, args := range commands {
+		c.write(args)
+	}
+	start := time.Now()
+	if err := c.flush(); err != nil {
+		return nil, errors.Wrap(err, "send commands")
+	}
+
+	replies := make([]respReply, len(commands))
+	for i := range replies {
+		reply, err := c.read()
+		if err != nil {
+			return nil, errors.Wrap(err, "read reply")
+		}
+		replies[i] = respReply{reply, time.Since(start)}
+	}
+	return replies, nil
+}
+
+// transaction runs commands in MULTI/EXEC, commands failed to queue get their error, others get EXEC result
+func (c *respConn) transaction(commands [][]string) ([]respReply, error) {
+	wrapped := append(append([][]string{{"MULTI"}}, commands...), []string{"EXEC"})
+	replies, err := c.pipeline(wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	queued, exec := replies[1:len(replies)-1], replies[len(replies)-1]
+	if multi := replies[0].Value; multi.Type == respError {
+		return nil, errors.Errorf("MULTI: %s", multi.Str())
+	}
+
+	res := make([]respReply, len(commands))
+	results := exec.Value.Items
+	for i := range res {
+		switch {
+		case queued[i].Value.Type == respError:
+			res[i] = queued[i]
+		case exec.Value.Type != respArray && exec.Value.Type != respSet:
+			res[i] = exec // NOTE: transaction is aborted or discarded by WATCH
+		case i < len(results):
+			res[i] = respReply{results[i], exec.Latency}
+		}
+	}
+	return res, nil
+}
+
+// dialRESP connects to server, negotiating RESP3 and falling back to RESP2 for old servers
+func dialRESP(ctx context.Context, addr string, opts respOptions, dial func(context.Context, string, string) (net.Conn, error)) (*respConn, error) {
+	if opts.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.DialTimeout)
+		defer cancel()
+	}
+
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial %s", addr)
+	}
+
+	c := &respConn{
+		conn:  conn,
+		r:     bufio.NewReader(conn),
+		w:     bufio.NewWriter(conn),
+		opts:  opts,
+		proto: 3,
+	}
+	if err := c.handshake(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *respConn) handshake(opts respOptions) error {
+	username := opts.Username
+	if username == "" {
+		username = "default"
+	}
+
+	hello := []string{"HELLO", "3"}
+	if opts.Password != "" {
+		hello = append(hello, "AUTH", username, opts.Password)
+	}
+	var err error
+	if opts.Protocol == 2 {
+		err = errors.New("NOPROTO RESP2 is requested")
+	} else {
+		_, err = c.do(hello...)
+	}
+	if err != nil {
+		msg := err.Error()
+		if c.broken || !strings.HasPrefix(msg, "ERR unknown command") && !strings.HasPrefix(msg, "NOPROTO") {
+			return errors.Wrap(err, "HELLO")
+		}
+
+		c.proto = 2
+		if opts.Password != "" {
+			auth := []string{"AUTH", opts.Password}
+			if opts.Username != "" {
+				auth = []string{"AUTH", opts.Username, opts.Password}
+			}
+			if _, err := c.do(auth...); err != nil {
+				return errors.Wrap(err, "AUTH")
+			}
+		}
+	}
+
+	if opts.ClientName != "" {
+		if _, err := c.do("CLIENT", "SETNAME", opts.ClientName); err != nil {
+			return errors.Wrap(err, "CLIENT SETNAME")
+		}
+	}
+
+	if opts.DB != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(opts.DB)); err != nil {
+			return errors.Wrap(err, "SELECT")
+		}
+	}
+	return nil
+}
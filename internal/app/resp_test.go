@@ -0,0 +1,134 @@
+package app
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRespRead(t *testing.T) {
+	str := func(s string) respValue { return respValue{Type: respBulkString, Value: s} }
+	integer := func(n int64) respValue { return respValue{Type: respInteger, Value: n} }
+
+	for _, tc := range []struct {
+		name  string
+		reply string
+		want  respValue
+	}{
+		{"simple string", "+OK\r\n", respValue{Type: respSimpleString, Value: "OK"}},
+		{"error", "-ERR unknown command\r\n", respValue{Type: respError, Value: "ERR unknown command"}},
+		{"integer", ":-42\r\n", integer(-42)},
+		{"bulk string", "$5\r\nhello\r\n", str("hello")},
+		{"empty bulk string", "$0\r\n\r\n", str("")},
+		{"bulk string with crlf", "$4\r\na\r\nb\r\n", str("a\r\nb")},
+		{"binary bulk string", "$2\r\n\xff\xfe\r\n", respValue{Type: respBulkString, Value: "//4=", Encoding: respEncodingBytes}},
+		{"null bulk string", "$-1\r\n", respValue{Type: respNull}},
+		{"null array", "*-1\r\n", respValue{Type: respNull}},
+		{"null", "_\r\n", respValue{Type: respNull}},
+		{"double", ",3.14\r\n", respValue{Type: respDouble, Value: 3.14}},
+		{"double exponent", ",1e3\r\n", respValue{Type: respDouble, Value: 1000.0}},
+		{"double inf", ",inf\r\n", respValue{Type: respDouble, Value: "inf"}},
+		{"double negative inf", ",-inf\r\n", respValue{Type: respDouble, Value: "-inf"}},
+		{"double nan", ",nan\r\n", respValue{Type: respDouble, Value: "nan"}},
+		{"boolean true", "#t\r\n", respValue{Type: respBoolean, Value: true}},
+		{"boolean false", "#f\r\n", respValue{Type: respBoolean, Value: false}},
+		{"big number", "(3492890328409238509324850943850943825024385\r\n", respValue{Type: respBigNumber, Value: "3492890328409238509324850943850943825024385"}},
+		{"negative big number", "(-3492890328409238509324850943850943825024385\r\n", respValue{Type: respBigNumber, Value: "-3492890328409238509324850943850943825024385"}},
+		{"blob error", "!21\r\nSYNTAX invalid syntax\r\n", respValue{Type: respError, Value: "SYNTAX invalid syntax"}},
+		{"verbatim string", "=15\r\ntxt:Some string\r\n", respValue{Type: respVerbatim, Value: "Some string", Format: "txt"}},
+		{"empty array", "*0\r\n", respValue{Type: respArray, Items: []respValue{}}},
+		{"array", "*3\r\n:1\r\n$1\r\na\r\n_\r\n", respValue{Type: respArray, Items: []respValue{integer(1), str("a"), {Type: respNull}}}},
+		{"nested array", "*2\r\n*1\r\n:1\r\n*0\r\n", respValue{Type: respArray, Items: []respValue{
+			{Type: respArray, Items: []respValue{integer(1)}},
+			{Type: respArray, Items: []respValue{}},
+		}}},
+		{"set", "~2\r\n+a\r\n+b\r\n", respValue{Type: respSet, Items: []respValue{
+			{Type: respSimpleString, Value: "a"},
+			{Type: respSimpleString, Value: "b"},
+		}}},
+		{"map", "%2\r\n+first\r\n:1\r\n$6\r\nsecond\r\n#t\r\n", respValue{Type: respMap, Entries: []respEntry{
+			{respValue{Type: respSimpleString, Value: "first"}, integer(1)},
+			{str("second"), respValue{Type: respBoolean, Value: true}},
+		}}},
+		{"map with non string keys", "%1\r\n:1\r\n*1\r\n:2\r\n", respValue{Type: respMap, Entries: []respEntry{
+			{integer(1), respValue{Type: respArray, Items: []respValue{integer(2)}}},
+		}}},
+		{"push", ">3\r\n$7\r\nmessage\r\n$4\r\nchan\r\n$2\r\nhi\r\n", respValue{Type: respPush, Items: []respValue{str("message"), str("chan"), str("hi")}}},
+		{"attribute", "|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.19\r\n*1\r\n:2039\r\n", respValue{Type: respArray, Items: []respValue{integer(2039)}}},
+		{"nested attribute", "*2\r\n|1\r\n+ttl\r\n:3600\r\n:1\r\n:2\r\n", respValue{Type: respArray, Items: []respValue{integer(1), integer(2)}}},
+		{"lf only", "+OK\n", respValue{Type: respSimpleString, Value: "OK"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &respConn{r: bufio.NewReader(strings.NewReader(tc.reply))}
+			got, err := c.read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %#v, want %#v", got, tc.want)
+			}
+			if rest, _ := c.r.Peek(1); len(rest) > 0 {
+				t.Fatalf("reply is not read fully, %d bytes left", c.r.Buffered())
+			}
+		})
+	}
+}
+
+func TestRespReadErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		reply string
+	}{
+		{"empty line", "\r\n"},
+		{"unknown type", "@1\r\n"},
+		{"invalid integer", ":abc\r\n"},
+		{"invalid double", ",abc\r\n"},
+		{"invalid size", "$abc\r\n"},
+		{"streamed string", "$?\r\n;4\r\nabcd\r\n;0\r\n"},
+		{"streamed array", "*?\r\n:1\r\n.\r\n"},
+		{"no line end", "+OK"},
+		{"truncated bulk string", "$5\r\nhel"},
+		{"truncated array", "*2\r\n:1\r\n"},
+		{"truncated map", "%1\r\n+key\r\n"},
+		{"truncated attribute", "|1\r\n+key\r\n:1\r\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &respConn{r: bufio.NewReader(strings.NewReader(tc.reply))}
+			if got, err := c.read(); err == nil {
+				t.Fatalf("expected error, got %#v", got)
+			}
+			if !c.broken {
+				t.Fatal("connection is not marked broken")
+			}
+		})
+	}
+}
+
+func TestRespBlockTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want time.Duration
+	}{
+		{[]string{"GET", "key"}, 0},
+		{[]string{"BLPOP", "a", "b", "5"}, 5 * time.Second},
+		{[]string{"brpop", "a", "0.5"}, 500 * time.Millisecond},
+		{[]string{"BLPOP", "a", "0"}, -1},
+		{[]string{"BLMOVE", "a", "b", "LEFT", "RIGHT", "2"}, 2 * time.Second},
+		{[]string{"BLMPOP", "3", "1", "a", "LEFT"}, 3 * time.Second},
+		{[]string{"BZMPOP", "0", "1", "a", "MIN"}, -1},
+		{[]string{"WAIT", "1", "100"}, 100 * time.Millisecond},
+		{[]string{"XREAD", "COUNT", "1", "BLOCK", "2000", "STREAMS", "s", "$"}, 2 * time.Second},
+		{[]string{"XREAD", "BLOCK", "0", "STREAMS", "s", "$"}, -1},
+		{[]string{"XREAD", "STREAMS", "BLOCK", "0"}, 0},
+		{[]string{"XREADGROUP", "GROUP", "g", "c", "BLOCK", "10", "STREAMS", "s", ">"}, 10 * time.Millisecond},
+		{[]string{"BLPOP", "a", "invalid"}, 0},
+	} {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			if got := respBlockTimeout(tc.args); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
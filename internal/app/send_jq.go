@@ -4,77 +4,360 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/itchyny/gojq"
 	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 
 	"github.com/rprtr258/impulse/internal/database"
 )
 
-func jq(ctx context.Context, input any, query string) ([]string, error) {
-	result, err := func() ([]string, error) {
-		query, err := gojq.Parse(query)
-		if err != nil {
-			return nil, errors.Wrap(err, "parse query")
+// jqModuleLoader loads modules and json data imported by queries from library directory of workspace
+type jqModuleLoader struct {
+	fs afero.Fs
+}
+
+func (l jqModuleLoader) read(name, ext string) (string, string, error) {
+	// NOTE: module "a/b" is looked up as a/b.jq and a/b/b.jq, like jq does
+	for _, path := range []string{name + ext, filepath.Join(name, filepath.Base(name)+ext)} {
+		b, err := afero.ReadFile(l.fs, path)
+		if err == nil {
+			return path, string(b), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", errors.Wrapf(err, "read module %q", name)
 		}
+	}
+	return "", "", errors.Errorf("module not found: %q", name)
+}
 
-		var result []string
-		iter := query.RunWithContext(ctx, input)
-		for {
-			v, ok := iter.Next()
-			if !ok {
-				break
+func (l jqModuleLoader) LoadModule(name string) (*gojq.Query, error) {
+	path, module, err := l.read(name, ".jq")
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := gojq.Parse(module)
+	if err != nil {
+		return nil, jqError(path, module, err)
+	}
+	return query, nil
+}
+
+func (l jqModuleLoader) LoadJSON(name string) (any, error) {
+	path, data, err := l.read(name, ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := jqDecode(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s", path)
+	}
+	return values, nil
+}
+
+// jqErrorPosition returns offset of token where query parsing failed.
+// NOTE: gojq does not report positions of compile errors, so first usage of undefined name in code is located.
+func jqErrorPosition(query string, err error) (int, bool) {
+	if err, ok := err.(*gojq.ParseError); ok {
+		return min(max(err.Offset-len(err.Token), 0), len(query)), true
+	}
+
+	for _, prefix := range []string{"function not defined: ", "variable not defined: "} {
+		if name, ok := strings.CutPrefix(err.Error(), prefix); ok {
+			name, _, _ = strings.Cut(name, "/") // NOTE: arity of function
+			if i := jqFindName(query, name); i != -1 {
+				return i, true
 			}
-			if err, ok := v.(error); ok {
-				if err, ok := err.(*gojq.HaltError); ok && err.Value() == nil {
-					break
+		}
+	}
+	return 0, false
+}
+
+func isJQNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// isJQObjectKey reports whether query[start:end] is key of object construction like {name: 1}
+func isJQObjectKey(query string, start, end int) bool {
+	before := strings.TrimRight(query[:start], " \t\n")
+	return strings.HasPrefix(strings.TrimLeft(query[end:], " \t\n"), ":") &&
+		(strings.HasSuffix(before, "{") || strings.HasSuffix(before, ","))
+}
+
+// jqFindName returns offset of first usage of function or variable name in query code,
+// string literals and comments are skipped, interpolations inside strings are searched.
+// Returns -1 if name is not found.
+func jqFindName(query, name string) int {
+	// NOTE: each element is count of open parens in interpolation, empty stack is top level code
+	var interpolations []int
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if inString {
+			switch {
+			case c == '\\' && i+1 < len(query) && query[i+1] == '(':
+				interpolations = append(interpolations, 0)
+				inString = false
+				i++
+			case c == '\\':
+				i++ // NOTE: escaped character
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+			continue
+		case '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			continue
+		case '(':
+			if len(interpolations) > 0 {
+				interpolations[len(interpolations)-1]++
+			}
+		case ')':
+			if len(interpolations) > 0 {
+				if interpolations[len(interpolations)-1] == 0 {
+					interpolations = interpolations[:len(interpolations)-1]
+					inString = true
+					continue
 				}
-				return nil, errors.Wrap(err, "run query")
+				interpolations[len(interpolations)-1]--
 			}
-			var sb strings.Builder
-			e := json.NewEncoder(&sb)
-			e.SetIndent("", "  ")
-			if err := e.Encode(v); err != nil {
-				return nil, errors.Wrap(err, "encode result")
+		}
+
+		if !strings.HasPrefix(query[i:], name) ||
+			i > 0 && (isJQNameChar(query[i-1]) || query[i-1] == '$' || query[i-1] == '.') ||
+			i+len(name) < len(query) && isJQNameChar(query[i+len(name)]) ||
+			isJQObjectKey(query, i, i+len(name)) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// jqError adds line and column of error position in query source
+func jqError(source, query string, err error) error {
+	offset, ok := jqErrorPosition(query, err)
+	if !ok {
+		return err
+	}
+
+	line := strings.Count(query[:offset], "\n") + 1
+	column := offset - strings.LastIndex(query[:offset], "\n")
+	return errors.Wrapf(err, "%s:%d:%d", source, line, column)
+}
+
+// jqVariables returns names and values of variables for args, named ones are also available as $ARGS.named
+func jqVariables(args []database.JQArg) ([]string, []any, error) {
+	names := make([]string, 0, len(args)+1)
+	values := make([]any, 0, len(args)+1)
+	named := make(map[string]any, len(args))
+	for _, arg := range args {
+		name := strings.TrimPrefix(arg.Name, "$")
+		if name == "" {
+			return nil, nil, errors.New("variable name is empty")
+		}
+
+		var value any = arg.Value
+		if arg.JSON {
+			if err := json.Unmarshal([]byte(arg.Value), &value); err != nil {
+				return nil, nil, errors.Wrapf(err, "parse value of $%s", name)
 			}
-			result = append(result, strings.TrimSpace(sb.String()))
 		}
-		return result, nil
-	}()
+
+		names = append(names, "$"+name)
+		values = append(values, value)
+		named[name] = value
+	}
+
+	names = append(names, "$ARGS")
+	values = append(values, map[string]any{"positional": []any{}, "named": named})
+	return names, values, nil
+}
+
+// jqCompile compiles query with variables, environment as $ENV and modules from workspace library
+func (a *App) jqCompile(query string, names []string) (*gojq.Code, error) {
+	q, err := gojq.Parse(query)
 	if err != nil {
-		return nil, err
+		return nil, jqError("query", query, err)
 	}
 
+	code, err := gojq.Compile(q,
+		gojq.WithVariables(names),
+		gojq.WithEnvironLoader(os.Environ),
+		gojq.WithModuleLoader(jqModuleLoader{a.jqModules}),
+	)
+	if err != nil {
+		return nil, errors.Wrap(jqError("query", query, err), "compile")
+	}
+	return code, nil
+}
+
+func jq(ctx context.Context, code *gojq.Code, input any, values []any) ([]any, error) {
+	var result []any
+	iter := code.RunWithContext(ctx, input, values...)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			if err, ok := err.(*gojq.HaltError); ok && err.Value() == nil {
+				break
+			}
+			return nil, errors.Wrap(err, "run query")
+		}
+		result = append(result, v)
+	}
 	return result, nil
 }
 
-func sendJQ(ctx context.Context, request database.JQRequest) (database.JQResponse, error) {
-	d := json.NewDecoder(strings.NewReader(request.JSON))
+// jqDecode parses stream of json values, numbers are kept as json.Number to not lose big integers
+func jqDecode(data string) ([]any, error) {
+	d := json.NewDecoder(strings.NewReader(data))
+	d.UseNumber()
 
-	resps := []string{}
+	inputs := []any{}
 	for {
 		var jsonv any
 		if err := d.Decode(&jsonv); err != nil {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return database.JQResponse{}, err
+			return nil, err
 		}
+		inputs = append(inputs, jsonv)
+	}
+	return inputs, nil
+}
+
+// jqResponseInputs converts response of request to jq input values, http body is parsed in given format
+func jqResponseInputs(response database.ResponseData, format database.JQInputFormat) ([]any, error) {
+	var data string
+	switch response := response.(type) {
+	case database.HTTPResponse:
+		inputs, err := jqParse(format, response.Body)
+		if err != nil {
+			return []any{response.Body}, nil // NOTE: body is not in format, use it as string
+		}
+		return inputs, nil
+	case database.GRPCResponse:
+		data = response.Response
+	case database.RedisResponse:
+		data = response.Response
+	case database.JQResponse:
+		switch response.Format {
+		case database.JQOutputRaw:
+			inputs := make([]any, len(response.Response))
+			for i, s := range response.Response {
+				inputs[i] = s
+			}
+			return inputs, nil
+		case database.JQOutputYAML:
+			return jqParse(database.JQInputYAML, strings.Join(response.Response, "\n---\n"))
+		case database.JQOutputCSV:
+			return jqParse(database.JQInputCSV, strings.Join(response.Response, ""))
+		default:
+			data = strings.Join(response.Response, "\n")
+		}
+	case database.SQLResponse:
+		rows := make([]map[string]any, len(response.Rows))
+		for i, row := range response.Rows {
+			rows[i] = make(map[string]any, len(response.Columns))
+			for j, column := range response.Columns {
+				if j < len(row) {
+					rows[i][column] = row[j]
+				}
+			}
+		}
+
+		b, err := json.Marshal(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal rows")
+		}
+		data = string(b)
+	default:
+		return nil, errors.Errorf("responses of type %T can't be used as input", response)
+	}
+	return jqDecode(data)
+}
 
-		resp, err := jq(ctx, jsonv, request.Query)
+// jqInputs returns json values from request or from response of referenced request
+func (a *App) jqInputs(request database.JQRequest) ([]any, error) {
+	if request.Input == "" {
+		return jqParse(request.InputFormat, request.JSON)
+	}
+
+	input, err := database.Get(a.ctx, a.DB, request.Input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get input request id=%q", request.Input)
+	}
+
+	entry, err := historyAt(input, request.InputIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := jqResponseInputs(entry.Response, request.InputFormat)
+	if err != nil {
+		return nil, errors.Wrapf(err, "input request id=%q", request.Input)
+	}
+	return inputs, nil
+}
+
+func (a *App) sendJQ(inputs []any, request database.JQRequest) (database.JQResponse, error) {
+	names, values, err := jqVariables(request.Args)
+	if err != nil {
+		return database.JQResponse{}, errors.Wrap(err, "args")
+	}
+
+	code, err := a.jqCompile(request.Query, names)
+	if err != nil {
+		return database.JQResponse{}, err
+	}
+
+	results := []any{}
+	for _, jsonv := range inputs {
+		result, err := jq(a.ctx, code, jsonv, values)
 		if err != nil {
 			return database.JQResponse{}, err
 		}
-		resps = append(resps, resp...)
+		results = append(results, result...)
+	}
+
+	format := request.OutputFormat
+	if format == "" {
+		format = database.JQOutputJSON
+	}
+	resps, err := jqFormat(format, results)
+	if err != nil {
+		return database.JQResponse{}, err
 	}
 	return database.JQResponse{
 		Response: resps,
+		Format:   format,
 	}, nil
 }
 
 // HandlerSend create a handler that performs call and save result to history
 func (a *App) JQ(json, query string) ([]string, error) {
-	resp, err := sendJQ(a.ctx, database.JQRequest{query, json})
+	inputs, err := jqDecode(json)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.sendJQ(inputs, database.JQRequest{Query: query})
 	return resp.Response, err
 }
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/itchyny/gojq"
+)
+
+func TestJQFindName(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		query string
+		find  string
+		want  int
+	}{
+		{"plain", ". | foo", "foo", 4},
+		{"not found", ". | bar", "foo", -1},
+		{"inside string", `"foo" | foo`, "foo", 8},
+		{"only inside string", `"foo"`, "foo", -1},
+		{"escaped quote in string", `"a\"foo" | foo`, "foo", 11},
+		{"inside interpolation", `"x \(foo)"`, "foo", 5},
+		{"string inside interpolation", `"\("foo") \(foo)"`, "foo", 12},
+		{"parens inside interpolation", `"\((1) | foo)"`, "foo", 9},
+		{"after interpolation", `"\(1) foo" | foo`, "foo", 13},
+		{"after comment", "# foo\nfoo", "foo", 6},
+		{"only in comment", ". # foo", "foo", -1},
+		{"object key", "{foo: 1} | foo", "foo", 11},
+		{"second object key", "{a: 1, foo : 2} | foo", "foo", 18},
+		{"object value", "{a: foo}", "foo", 4},
+		{"field", ".foo | foo", "foo", 7},
+		{"optional field", ".a.foo? | foo", "foo", 10},
+		{"variable is not function", "$foo | foo", "foo", 7},
+		{"function is not variable", "foo | $foo", "$foo", 6},
+		{"prefix of name", "foobar | foo", "foo", 9},
+		{"suffix of name", "barfoo | foo", "foo", 9},
+		{"variable prefix", "$foobar | $foo", "$foo", 10},
+		{"variable in slice", ".[$x:]", "$x", 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := jqFindName(tc.query, tc.find); got != tc.want {
+				t.Fatalf("got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJQErrorPosition(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		query  string
+		want   int
+		wantOK bool
+	}{
+		{"undefined function", `"foo" | {foo: .foo} | foo`, 22, true},
+		{"undefined function with args", "foo(1) | . as $x | $x", 0, true},
+		{"undefined variable", `"$x" | $x`, 7, true},
+		{"undefined in interpolation", `"\(bar)"`, 3, true},
+		{"parse error", ". | ]", 4, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := gojq.Parse(tc.query)
+			if err == nil {
+				_, err = gojq.Compile(query)
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			got, ok := jqErrorPosition(tc.query, err)
+			if ok != tc.wantOK || got != tc.want {
+				t.Fatalf("got %d, %v, want %d, %v (%v)", got, ok, tc.want, tc.wantOK, err)
+			}
+		})
+	}
+}
@@ -1,56 +1,366 @@
 package app
 
 import (
-	"context"
 	"encoding/json"
-	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
-	"github.com/redis/go-redis/v9"
 
 	"github.com/rprtr258/impulse/internal/database"
 )
 
-func sendRedis(ctx context.Context, request database.RedisRequest) (database.RedisResponse, error) {
-	uri, err := url.Parse(request.DSN)
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// splitRedisArgs splits command line into arguments the same way redis-cli does:
+// arguments are separated by spaces, double quoted ones support \n, \r, \t, \b, \a and \xHH escapes,
+// single quoted ones support only \' escape
+func splitRedisArgs(line string) ([]string, error) {
+	var args []string
+	for i := 0; ; {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t' || line[i] == '\r' || line[i] == '\n') {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var arg strings.Builder
+		inDouble, inSingle := false, false
+		for done := false; !done; {
+			switch {
+			case inDouble:
+				switch {
+				case i == len(line):
+					return nil, errors.New("unbalanced double quotes")
+				case line[i] == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHexDigit(line[i+2]) && isHexDigit(line[i+3]):
+					b, _ := strconv.ParseUint(line[i+2:i+4], 16, 8)
+					arg.WriteByte(byte(b))
+					i += 3
+				case line[i] == '\\' && i+1 < len(line):
+					i++
+					switch c := line[i]; c {
+					case 'n':
+						arg.WriteByte('\n')
+					case 'r':
+						arg.WriteByte('\r')
+					case 't':
+						arg.WriteByte('\t')
+					case 'b':
+						arg.WriteByte('\b')
+					case 'a':
+						arg.WriteByte('\a')
+					default:
+						arg.WriteByte(c)
+					}
+				case line[i] == '"':
+					// NOTE: closing quote must be followed by space or nothing
+					if i+1 < len(line) && line[i+1] != ' ' && line[i+1] != '\t' {
+						return nil, errors.New("closing quote must be followed by space")
+					}
+					done = true
+				default:
+					arg.WriteByte(line[i])
+				}
+			case inSingle:
+				switch {
+				case i == len(line):
+					return nil, errors.New("unbalanced single quotes")
+				case line[i] == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					arg.WriteByte('\'')
+				case line[i] == '\'':
+					if i+1 < len(line) && line[i+1] != ' ' && line[i+1] != '\t' {
+						return nil, errors.New("closing quote must be followed by space")
+					}
+					done = true
+				default:
+					arg.WriteByte(line[i])
+				}
+			default:
+				switch {
+				case i == len(line), line[i] == ' ', line[i] == '\t', line[i] == '\r', line[i] == '\n':
+					done = true
+					continue
+				case line[i] == '"':
+					inDouble = true
+				case line[i] == '\'':
+					inSingle = true
+				default:
+					arg.WriteByte(line[i])
+				}
+			}
+			i++
+		}
+		args = append(args, arg.String())
+	}
+}
+
+// parseRedisQuery returns arguments of every command, one command per line
+func parseRedisQuery(query string) ([][]string, error) {
+	var commands [][]string
+	for i, line := range strings.Split(query, "\n") {
+		args, err := splitRedisArgs(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", i+1)
+		}
+		if len(args) > 0 {
+			commands = append(commands, args)
+		}
+	}
+	if len(commands) == 0 {
+		return nil, errors.New("no commands given")
+	}
+	return commands, nil
+}
+
+func (a *App) getRedisRequest(id string) (database.RedisRequest, error) {
+	request, err := database.Get(a.ctx, a.DB, database.RequestID(id))
 	if err != nil {
-		return database.RedisResponse{}, errors.Wrapf(err, "parse dsn %q", request.DSN)
+		return database.RedisRequest{}, errors.Wrapf(err, "get request id=%q", id)
 	}
 
-	db := 0
-	if len(uri.Path) > 1 && uri.Path[1:] != "" { // NOTE: handle both "" and "/"
-		if db, err = strconv.Atoi(uri.Path[1:]); err != nil {
-			return database.RedisResponse{}, errors.Wrapf(err, "parse db %q", request.DSN)
+	req, ok := request.Data.(database.RedisRequest)
+	if !ok {
+		return database.RedisRequest{}, errors.Errorf("query kind is %s, expected redis", request.Data.Kind())
+	}
+	return req, nil
+}
+
+// redisRoute returns node address for every command, empty for commands without keys in cluster.
+// Keyed commands of transaction must be served by the same node.
+func (a *App) redisRoute(t *redisTopology, commands [][]string, transaction bool) ([]string, error) {
+	nodes := make([]string, len(commands))
+	if t.cluster == nil {
+		for i := range nodes {
+			nodes[i] = t.master
 		}
+		return nodes, nil
 	}
 
-	password, _ := uri.User.Password()
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     uri.Host,
-		Username: uri.User.Username(),
-		Password: password,
-		DB:       db,
-	})
+	for i, command := range commands {
+		keys, err := a.commandKeys(t, command)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			node, err := t.nodeOf(a.ctx, key)
+			if err != nil {
+				return nil, err
+			}
 
-	// TODO: breaks on something like SET key "barabem barabum"
-	args := []any{}
-	for arg := range strings.FieldsSeq(request.Query) {
-		args = append(args, arg)
+			switch {
+			case nodes[i] == "":
+				nodes[i] = node.Addr
+			case nodes[i] != node.Addr:
+				return nil, errors.Errorf("command %d: keys are served by different nodes %s and %s", i+1, nodes[i], node.Addr)
+			}
+		}
+	}
+
+	if !transaction {
+		return nodes, nil
+	}
+
+	target := ""
+	for i, node := range nodes {
+		switch {
+		case node == "":
+		case target == "":
+			target = node
+		case target != node:
+			return nil, errors.Errorf("transaction keys are served by different nodes %s and %s, command %d", target, node, i+1)
+		}
+	}
+	if target == "" {
+		masters, err := t.masters(a.ctx)
+		if err != nil {
+			return nil, err
+		}
+		target = masters[0].Addr
 	}
+	for i := range nodes {
+		nodes[i] = target
+	}
+	return nodes, nil
+}
 
-	val, err := rdb.Do(ctx, args...).Result()
+// redisFollow follows cluster MOVED and ASK redirects of command reply
+func (a *App) redisFollow(t *redisTopology, command []string, reply respReply, addr string) (respReply, string, error) {
+	for range 5 {
+		node, ask, ok := t.redirect(a.ctx, reply.Value)
+		if !ok {
+			break
+		}
+
+		cmds := [][]string{command}
+		if ask {
+			cmds = [][]string{{"ASKING"}, command}
+		}
+
+		var res []respReply
+		if err := a.redisDo(t.ssh, node, func(conn *respConn) error {
+			var err error
+			res, err = conn.pipeline(cmds)
+			return err
+		}); err != nil {
+			return respReply{}, "", errors.Wrapf(err, "redirect to node %s", node.Addr)
+		}
+		reply, addr = res[len(res)-1], node.Addr
+	}
+	return reply, addr, nil
+}
+
+// redisFanOut runs command without keys on every cluster master, reply is map of node address to its reply
+func (a *App) redisFanOut(t *redisTopology, command []string) (respReply, string, int, error) {
+	masters, err := t.masters(a.ctx)
 	if err != nil {
-		return database.RedisResponse{}, errors.Wrapf(err, "process query %q", request.Query)
+		return respReply{}, "", 0, err
 	}
 
-	b, err := json.Marshal(val)
+	reply := respReply{Value: respValue{Type: respMap}}
+	addrs := make([]string, len(masters))
+	proto := 0
+	for i, node := range masters {
+		var res []respReply
+		if err := a.redisDo(t.ssh, node, func(conn *respConn) error {
+			proto = conn.proto
+			var err error
+			res, err = conn.pipeline([][]string{command})
+			return err
+		}); err != nil {
+			return respReply{}, "", 0, errors.Wrapf(err, "node %s", node.Addr)
+		}
+
+		reply.Value.Entries = append(reply.Value.Entries, respEntry{respValue{Type: respBulkString, Value: node.Addr}, res[0].Value})
+		reply.Latency += res[0].Latency
+		addrs[i] = node.Addr
+	}
+	return reply, strings.Join(addrs, ","), proto, nil
+}
+
+// redisExec runs commands in given order on nodes serving their keys, following cluster redirects.
+// Consecutive commands for the same node are pipelined, commands without keys run on every cluster master.
+// Returns replies, addresses of nodes answered and protocol version.
+func (a *App) redisExec(t *redisTopology, commands [][]string, transaction bool) ([]respReply, []string, int, error) {
+	nodes, err := a.redisRoute(t, commands, transaction)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	replies := make([]respReply, len(commands))
+	answered := make([]string, len(commands))
+	proto := 0
+	for start := 0; start < len(commands); {
+		if nodes[start] == "" {
+			replies[start], answered[start], proto, err = a.redisFanOut(t, commands[start])
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			start++
+			continue
+		}
+
+		end := start + 1
+		for end < len(commands) && nodes[end] == nodes[start] {
+			end++
+		}
+
+		node := t.node(nodes[start])
+		var res []respReply
+		if err := a.redisDo(t.ssh, node, func(conn *respConn) error {
+			proto = conn.proto
+			var err error
+			if transaction {
+				res, err = conn.transaction(commands[start:end])
+			} else {
+				res, err = conn.pipeline(commands[start:end])
+			}
+			return err
+		}); err != nil {
+			return nil, nil, 0, errors.Wrapf(err, "node %s", node.Addr)
+		}
+
+		for i := start; i < end; i++ {
+			replies[i], answered[i] = res[i-start], node.Addr
+			if transaction {
+				continue
+			}
+
+			// NOTE: slots may migrate, so commands are redirected by MOVED and ASK errors
+			if replies[i], answered[i], err = a.redisFollow(t, commands[i], replies[i], answered[i]); err != nil {
+				return nil, nil, 0, err
+			}
+		}
+		start = end
+	}
+	return replies, answered, proto, nil
+}
+
+func (a *App) sendRedis(id database.RequestID, request database.RedisRequest) (database.RedisResponse, error) {
+	if request.Mode != database.RedisModeQuery && request.Mode != "" {
+		return a.subscribeRedis(id, request)
+	}
+
+	commands, err := parseRedisQuery(request.Query)
+	if err != nil {
+		return database.RedisResponse{}, errors.Wrap(err, "parse query")
+	}
+
+	topology, err := a.redisTopology(request)
+	if err != nil {
+		return database.RedisResponse{}, err
+	}
+
+	replies, nodes, proto, err := a.redisExec(topology, commands, request.Transaction)
+	if err != nil {
+		a.forgetRedisTopology(request, topology) // NOTE: deployment may have changed, e.g. after failover
+		return database.RedisResponse{}, errors.Wrap(err, "process query")
+	}
+
+	results := make([]database.RedisResult, len(replies))
+	values := make([]any, len(replies))
+	for i, reply := range replies {
+		value, err := json.Marshal(reply.Value)
+		if err != nil {
+			return database.RedisResponse{}, errors.Wrap(err, "marshal reply")
+		}
+
+		results[i] = database.RedisResult{
+			Command: strings.Join(commands[i], " "),
+			Value:   string(value),
+			Latency: reply.Latency,
+			Node:    nodes[i],
+		}
+		values[i] = reply.Value.Plain()
+		if reply.Value.Type == respError {
+			results[i].Error = reply.Value.Str()
+			continue
+		}
+
+		b, err := json.Marshal(values[i])
+		if err != nil {
+			return database.RedisResponse{}, errors.Wrap(err, "marshal result")
+		}
+		results[i].Response = string(b)
+	}
+
+	var response []byte
+	if len(values) == 1 {
+		response, err = json.Marshal(values[0])
+	} else {
+		response, err = json.Marshal(values)
+	}
 	if err != nil {
-		return database.RedisResponse{}, errors.Wrapf(err, "marshal result %v", val)
+		return database.RedisResponse{}, errors.Wrap(err, "marshal results")
 	}
 
 	return database.RedisResponse{
-		Response: string(b),
+		Response: string(response),
+		Protocol: proto,
+		Results:  results,
 	}, nil
 }
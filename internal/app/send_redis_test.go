@@ -0,0 +1,51 @@
+package app
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitRedisArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"spaces only", " \t ", nil, false},
+		{"plain", "SET key value", []string{"SET", "key", "value"}, false},
+		{"extra spaces", "  GET \t key  ", []string{"GET", "key"}, false},
+		{"double quotes", `SET "my key" "a b"`, []string{"SET", "my key", "a b"}, false},
+		{"single quotes", `SET 'my key' 'a "b"'`, []string{"SET", "my key", `a "b"`}, false},
+		{"empty quoted", `SET k ""`, []string{"SET", "k", ""}, false},
+		{"quotes inside word", `SET k a"b c"`, []string{"SET", "k", "ab c"}, false},
+		{"escapes", `SET k "a\nb\r\tc\b\a"`, []string{"SET", "k", "a\nb\r\tc\b\a"}, false},
+		{"escaped quote and backslash", `SET k "a\"b\\c"`, []string{"SET", "k", `a"b\c`}, false},
+		{"unknown escape", `SET k "\q"`, []string{"SET", "k", "q"}, false},
+		{"hex escape", `SET k "\x41\x00\xff"`, []string{"SET", "k", "A\x00\xff"}, false},
+		{"invalid hex escape", `SET k "\x4g"`, []string{"SET", "k", "x4g"}, false},
+		{"escapes in single quotes", `SET k 'a\nb\'c'`, []string{"SET", "k", `a\nb'c`}, false},
+		{"unbalanced double quotes", `SET k "abc`, nil, true},
+		{"unbalanced single quotes", `SET k 'abc`, nil, true},
+		{"trailing backslash", `SET k "abc\`, nil, true},
+		{"text after double quote", `SET k "a"b`, nil, true},
+		{"text after single quote", `SET k 'a'b`, nil, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := splitRedisArgs(tc.line)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
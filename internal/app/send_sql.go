@@ -1,12 +1,18 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"net"
+	"net/url"
+	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
 	"github.com/pkg/errors"
 	_ "modernc.org/sqlite"
@@ -45,32 +51,10 @@ func convertTypes(columns int, rows [][]any) []database.ColumnType {
 	return types
 }
 
-func (a *App) sendSQLClickhouse(dsn, query string) (database.SQLResponse, error) {
-	opts, err := clickhouse.ParseDSN(dsn)
-	if err != nil {
-		return database.SQLResponse{}, errors.Wrap(err, "parse DSN")
-	}
-
-	db := clickhouse.OpenDB(opts)
-	defer db.Close()
-	db.SetMaxIdleConns(5)
-	db.SetMaxOpenConns(10)
-	db.SetConnMaxLifetime(time.Hour)
-
-	if err := db.PingContext(a.ctx); err != nil {
-		return database.SQLResponse{}, errors.Wrap(err, "ping database")
-	}
-
-	// TODO: add limit
-	rows, err := db.Query(query)
-	if err != nil {
-		return database.SQLResponse{}, errors.Wrap(err, "query")
-	}
-	defer rows.Close()
-
+func scanRows(rows *sql.Rows) ([]string, [][]any, error) {
 	columns, err := rows.Columns()
 	if err != nil {
-		return database.SQLResponse{}, errors.Wrap(err, "get columns")
+		return nil, nil, errors.Wrap(err, "get columns")
 	}
 
 	var rowsData [][]any
@@ -81,99 +65,339 @@ func (a *App) sendSQLClickhouse(dsn, query string) (database.SQLResponse, error)
 			return &rowDest[i]
 		}, rowDest...)
 		if err := rows.Scan(dest...); err != nil {
-			return database.SQLResponse{}, errors.Wrap(err, "scan row")
+			return nil, nil, errors.Wrap(err, "scan row")
 		}
 
 		rowsData = append(rowsData, rowDest)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, "iterate rows")
+	}
 
-	return database.SQLResponse{
-		columns,
-		convertTypes(len(columns), rowsData),
-		rowsData,
-	}, nil
+	return columns, rowsData, nil
 }
 
-func (a *App) sendSQLSTD(db *sql.DB, query string) (database.SQLResponse, error) {
-	if err := db.PingContext(a.ctx); err != nil {
-		return database.SQLResponse{}, errors.Wrap(err, "ping database")
-	}
+// sqlConn is either connection or transaction statements are run on
+type sqlConn interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
 
+func querySQL(ctx context.Context, conn sqlConn, query string, args []any) (database.SQLResult, error) {
 	// TODO: add limit
-	rows, err := db.Query(query)
+	rows, err := conn.QueryContext(ctx, query, args...)
 	if err != nil {
-		return database.SQLResponse{}, errors.Wrap(err, "query")
+		return database.SQLResult{}, errors.Wrap(err, "query")
 	}
 	defer rows.Close()
 
-	columns, err := rows.Columns()
+	columns, rowsData, err := scanRows(rows)
 	if err != nil {
-		return database.SQLResponse{}, errors.Wrap(err, "get columns")
+		return database.SQLResult{}, err
 	}
 
-	var rowsData [][]any
-	for rows.Next() {
-		rowDest := make([]any, len(columns))
+	return database.SQLResult{
+		Query:   query,
+		Columns: columns,
+		Types:   convertTypes(len(columns), rowsData),
+		Rows:    rowsData,
+	}, nil
+}
 
-		dest := fun.Map[any](func(_ any, i int) any {
-			return &rowDest[i]
-		}, rowDest...)
-		if err := rows.Scan(dest...); err != nil {
-			return database.SQLResponse{}, errors.Wrap(err, "scan row")
+func execSQL(ctx context.Context, conn sqlConn, query string, args []any) (database.SQLResult, error) {
+	res, err := conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return database.SQLResult{}, errors.Wrap(err, "exec")
+	}
+
+	result := database.SQLResult{Query: query}
+	// NOTE: not every driver supports these, e.g. postgres has no last insert id
+	if n, err := res.RowsAffected(); err == nil {
+		result.RowsAffected = &n
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		result.LastInsertID = &id
+	}
+	return result, nil
+}
+
+// expandSQLParamEnv replaces ${NAME} with value of environment variable,
+// unset variables and other $ sequences like in pa$$word are kept as is
+func expandSQLParamEnv(value string) string {
+	var sb strings.Builder
+	for {
+		i := strings.Index(value, "${")
+		if i == -1 {
+			sb.WriteString(value)
+			return sb.String()
+		}
+		j := strings.IndexByte(value[i:], '}')
+		if j == -1 {
+			sb.WriteString(value)
+			return sb.String()
 		}
 
-		rowsData = append(rowsData, rowDest)
+		if env, ok := os.LookupEnv(value[i+2 : i+j]); ok {
+			sb.WriteString(value[:i])
+			sb.WriteString(env)
+		} else {
+			sb.WriteString(value[:i+j+1])
+		}
+		value = value[i+j+1:]
 	}
+}
 
-	return database.SQLResponse{
-		columns,
-		convertTypes(len(columns), rowsData),
-		rowsData,
-	}, nil
+func parseSQLParam(param database.SQLParam) (any, error) {
+	value := expandSQLParamEnv(param.Value)
+	switch param.Type {
+	case database.SQLParamTypeString, "":
+		return value, nil
+	case database.SQLParamTypeInt:
+		return strconv.ParseInt(value, 10, 64)
+	case database.SQLParamTypeFloat:
+		return strconv.ParseFloat(value, 64)
+	case database.SQLParamTypeBool:
+		return strconv.ParseBool(value)
+	case database.SQLParamTypeTime:
+		return time.Parse(time.RFC3339, value)
+	case database.SQLParamTypeNull:
+		return nil, nil
+	default:
+		return nil, errors.Errorf("unknown param type %q", param.Type)
+	}
 }
 
-func (a *App) sendSQLMysql(dsn, query string) (database.SQLResponse, error) {
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		return database.SQLResponse{}, errors.Wrap(err, "connect to database")
+// sqlBinder distributes request params among script statements
+type sqlBinder struct {
+	db         database.Database
+	positional []any
+	named      []sql.NamedArg
+	next       int // next positional param for ? placeholders
+}
+
+func newSQLBinder(db database.Database, params []database.SQLParam) (*sqlBinder, error) {
+	b := &sqlBinder{db: db}
+	for i, param := range params {
+		value, err := parseSQLParam(param)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse param #%d %q", i+1, param.Name)
+		}
+
+		if param.Name != "" && db == database.SQLite {
+			b.named = append(b.named, sql.Named(param.Name, value))
+		} else {
+			b.positional = append(b.positional, value)
+		}
 	}
-	defer db.Close()
+	return b, nil
+}
 
-	return a.sendSQLSTD(db, query)
+func (b *sqlBinder) args(stmt string) []any {
+	dollarMax, questions := countPlaceholders(stmt)
+	switch b.db {
+	case database.Postgres:
+		// NOTE: each statement has its own $1, $2, ... numbering
+		return b.positional[:min(dollarMax, len(b.positional))]
+	case database.MySQL, database.SQLite:
+		from := min(b.next, len(b.positional))
+		to := min(b.next+questions, len(b.positional))
+		b.next += questions
+		args := b.positional[from:to]
+		for _, arg := range b.named {
+			if hasNamedPlaceholder(stmt, arg.Name) {
+				args = append(args, arg)
+			}
+		}
+		return args
+	default: // NOTE: clickhouse params are passed through context
+		return nil
+	}
+}
+
+// withClickhouseParams passes params for {name:Type} placeholders of clickhouse
+func withClickhouseParams(ctx context.Context, params []database.SQLParam) (context.Context, error) {
+	chParams := make(clickhouse.Parameters, len(params))
+	for i, param := range params {
+		if param.Name == "" {
+			return nil, errors.Errorf("param #%d: clickhouse params must be named", i+1)
+		}
+		chParams[param.Name] = expandSQLParamEnv(param.Value)
+	}
+	return clickhouse.Context(ctx, clickhouse.WithParameters(chParams)), nil
 }
 
-func (a *App) sendSQLSqlite(dsn, query string) (database.SQLResponse, error) {
-	db, err := sql.Open("sqlite", dsn)
+func runSQLScript(ctx context.Context, conn sqlConn, req database.SQLRequest) (database.SQLResponse, error) {
+	if req.Database == database.Clickhouse && len(req.Params) > 0 {
+		var err error
+		if ctx, err = withClickhouseParams(ctx, req.Params); err != nil {
+			return database.SQLResponse{}, err
+		}
+	}
+
+	binder, err := newSQLBinder(req.Database, req.Params)
 	if err != nil {
-		return database.SQLResponse{}, errors.Wrap(err, "connect to database")
+		return database.SQLResponse{}, err
 	}
-	defer db.Close()
 
-	return a.sendSQLSTD(db, query)
+	response := database.SQLResponse{
+		Columns: nil,
+		Types:   []database.ColumnType{},
+		Rows:    nil,
+		Results: []database.SQLResult{},
+	}
+	for _, query := range splitStatements(req.Query) {
+		args := binder.args(query)
+
+		start := time.Now()
+		var result database.SQLResult
+		var err error
+		if req.Mode == database.SQLModeQuery || req.Mode != database.SQLModeExec && returnsRows(query) {
+			result, err = querySQL(ctx, conn, query, args)
+		} else {
+			result, err = execSQL(ctx, conn, query, args)
+		}
+		result.Query = query
+		result.Duration = time.Since(start)
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		response.Results = append(response.Results, result)
+		if result.Columns != nil {
+			response.Columns, response.Types, response.Rows = result.Columns, result.Types, result.Rows
+		}
+
+		if err != nil { // NOTE: stop on first error, as following statements might depend on failed one
+			break
+		}
+	}
+	return response, nil
 }
 
-func (a *App) sendSQLPostgres(dsn, query string) (database.SQLResponse, error) {
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return database.SQLResponse{}, errors.Wrap(err, "connect to database")
+// tunnelPostgresDSN replaces host and port in postgres url or key=value dsn with tunnel address
+func (a *App) tunnelPostgresDSN(cfg *database.SSHTunnel, dsn string) (string, error) {
+	if cfg == nil {
+		return dsn, nil
 	}
-	defer db.Close()
 
-	return a.sendSQLSTD(db, query)
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", errors.Wrap(err, "parse DSN")
+		}
+
+		host, port := u.Hostname(), u.Port()
+		if host == "" {
+			host = "localhost"
+		}
+		if port == "" {
+			port = "5432"
+		}
+		if u.Host, err = a.tunnel(cfg, net.JoinHostPort(host, port)); err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+
+	host, port := "localhost", "5432"
+	fields := []string{}
+	for field := range strings.FieldsSeq(dsn) {
+		switch k, v, _ := strings.Cut(field, "="); k {
+		case "host":
+			host = strings.Trim(v, "'")
+		case "port":
+			port = strings.Trim(v, "'")
+		default:
+			fields = append(fields, field)
+		}
+	}
+
+	local, err := a.tunnel(cfg, net.JoinHostPort(host, port))
+	if err != nil {
+		return "", err
+	}
+	host, port, _ = net.SplitHostPort(local)
+	return strings.Join(append(fields, "host="+host, "port="+port), " "), nil
 }
 
-func (a *App) sendSQL(req database.SQLRequest) (database.SQLResponse, error) {
-	dsn, query := req.DSN, req.Query
+func (a *App) openSQL(req database.SQLRequest) (*sql.DB, error) {
 	switch req.Database {
 	case database.Postgres:
-		return a.sendSQLPostgres(dsn, query)
+		dsn, err := a.tunnelPostgresDSN(req.SSH, req.DSN)
+		if err != nil {
+			return nil, errors.Wrap(err, "open ssh tunnel")
+		}
+		return sql.Open("postgres", dsn)
 	case database.Clickhouse:
-		return a.sendSQLClickhouse(dsn, query)
+		opts, err := clickhouse.ParseDSN(req.DSN)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse DSN")
+		}
+
+		if req.SSH != nil {
+			if opts.TLS != nil && opts.TLS.ServerName == "" && len(opts.Addr) > 0 {
+				// NOTE: keep verifying certificate against real host, not tunnel address
+				opts.TLS.ServerName, _, _ = net.SplitHostPort(opts.Addr[0])
+			}
+			for i, addr := range opts.Addr {
+				if opts.Addr[i], err = a.tunnel(req.SSH, addr); err != nil {
+					return nil, errors.Wrap(err, "open ssh tunnel")
+				}
+			}
+		}
+
+		db := clickhouse.OpenDB(opts)
+		db.SetMaxIdleConns(5)
+		db.SetMaxOpenConns(10)
+		db.SetConnMaxLifetime(time.Hour)
+		return db, nil
 	case database.SQLite:
-		return a.sendSQLSqlite(dsn, query)
+		if req.SSH != nil {
+			return nil, errors.New("ssh tunnel is not supported for sqlite")
+		}
+		return sql.Open("sqlite", req.DSN)
 	case database.MySQL:
-		return a.sendSQLMysql(dsn, query)
+		if req.SSH == nil {
+			return sql.Open("mysql", req.DSN)
+		}
+
+		cfg, err := mysql.ParseDSN(req.DSN)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse DSN")
+		}
+		if cfg.Net != "tcp" {
+			return nil, errors.Errorf("ssh tunnel is not supported for %s protocol", cfg.Net)
+		}
+		if cfg.Addr, err = a.tunnel(req.SSH, cfg.Addr); err != nil {
+			return nil, errors.Wrap(err, "open ssh tunnel")
+		}
+		return sql.Open("mysql", cfg.FormatDSN())
 	default:
-		return database.SQLResponse{}, errors.Errorf("unsupported database: %s", req.Database)
+		return nil, errors.Errorf("unsupported database: %s", req.Database)
+	}
+}
+
+func (a *App) sendSQL(id database.RequestID, req database.SQLRequest) (database.SQLResponse, error) {
+	if session, ok := a.getSQLSession(id); ok {
+		return session.run(a.ctx, req)
+	}
+
+	db, err := a.openSQL(req)
+	if err != nil {
+		return database.SQLResponse{}, errors.Wrap(err, "connect to database")
 	}
+	defer db.Close()
+
+	if err := db.PingContext(a.ctx); err != nil {
+		return database.SQLResponse{}, errors.Wrap(err, "ping database")
+	}
+
+	// NOTE: hold single connection, so that statements can depend on session state
+	conn, err := db.Conn(a.ctx)
+	if err != nil {
+		return database.SQLResponse{}, errors.Wrap(err, "get connection")
+	}
+	defer conn.Close()
+
+	return runSQLScript(a.ctx, conn, req)
 }
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+func TestExpandSQLParamEnv(t *testing.T) {
+	t.Setenv("IMPULSE_TEST_USER", "admin")
+	t.Setenv("IMPULSE_TEST_EMPTY", "")
+
+	for _, tc := range []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"${IMPULSE_TEST_USER}", "admin"},
+		{"user=${IMPULSE_TEST_USER}, again ${IMPULSE_TEST_USER}!", "user=admin, again admin!"},
+		{"${IMPULSE_TEST_EMPTY}x", "x"},
+		{"pa$$word", "pa$$word"},
+		{"$5", "$5"},
+		{"price$", "price$"},
+		{"$IMPULSE_TEST_USER", "$IMPULSE_TEST_USER"},
+		{"${IMPULSE_TEST_UNSET}", "${IMPULSE_TEST_UNSET}"},
+		{"${IMPULSE_TEST_USER", "${IMPULSE_TEST_USER"},
+		{"${}", "${}"},
+		{"$${IMPULSE_TEST_USER}", "$admin"},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			if got := expandSQLParamEnv(tc.value); got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSQLParam(t *testing.T) {
+	t.Setenv("IMPULSE_TEST_PORT", "5432")
+
+	for _, tc := range []struct {
+		name    string
+		param   database.SQLParam
+		want    any
+		wantErr bool
+	}{
+		{"string with dollars", database.SQLParam{"", database.SQLParamTypeString, "pa$$word"}, "pa$$word", false},
+		{"untyped", database.SQLParam{"", "", "$1"}, "$1", false},
+		{"int from env", database.SQLParam{"", database.SQLParamTypeInt, "${IMPULSE_TEST_PORT}"}, int64(5432), false},
+		{"float", database.SQLParam{"", database.SQLParamTypeFloat, "1.5"}, 1.5, false},
+		{"bool", database.SQLParam{"", database.SQLParamTypeBool, "true"}, true, false},
+		{"null", database.SQLParam{"", database.SQLParamTypeNull, "ignored"}, nil, false},
+		{"invalid int", database.SQLParam{"", database.SQLParamTypeInt, "$5"}, nil, true},
+		{"unknown type", database.SQLParam{"", "uuid", "x"}, nil, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseSQLParam(tc.param)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
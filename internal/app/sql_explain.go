@@ -0,0 +1,416 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+type sqlPlanNode struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	// Cost is estimated total cost in database units
+	Cost *float64 `json:"cost"`
+	// Rows is estimated number of rows
+	Rows       *float64 `json:"rows"`
+	ActualRows *float64 `json:"actual_rows"`
+	// Time is actual total time in milliseconds, known only when query was analyzed
+	Time     *float64       `json:"time"`
+	Extra    map[string]any `json:"extra"`
+	Children []sqlPlanNode  `json:"children"`
+}
+
+type SQLExplainResponse struct {
+	Query string      `json:"query"`
+	Plan  sqlPlanNode `json:"plan"`
+	// Raw is explain output as returned by database
+	Raw string `json:"raw"`
+}
+
+func newPlanNode(typ string) sqlPlanNode {
+	return sqlPlanNode{
+		Type:     typ,
+		Extra:    map[string]any{},
+		Children: []sqlPlanNode{},
+	}
+}
+
+func toFloat(v any) *float64 {
+	switch v := v.(type) {
+	case float64:
+		return &v
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return &f
+		}
+	}
+	return nil
+}
+
+func convertPostgresPlan(plan map[string]any) sqlPlanNode {
+	typ, _ := plan["Node Type"].(string)
+	node := newPlanNode(typ)
+	for _, key := range []string{"Relation Name", "Index Name", "CTE Name", "Function Name"} {
+		if v, ok := plan[key].(string); ok {
+			node.Detail = strings.TrimSpace(node.Detail + " " + v)
+		}
+	}
+	node.Cost = toFloat(plan["Total Cost"])
+	node.Rows = toFloat(plan["Plan Rows"])
+	node.ActualRows = toFloat(plan["Actual Rows"])
+	node.Time = toFloat(plan["Actual Total Time"])
+	for k, v := range plan {
+		switch k {
+		case "Node Type", "Total Cost", "Plan Rows", "Actual Rows", "Actual Total Time":
+		case "Plans":
+			children, _ := v.([]any)
+			for _, child := range children {
+				if child, ok := child.(map[string]any); ok {
+					node.Children = append(node.Children, convertPostgresPlan(child))
+				}
+			}
+		default:
+			node.Extra[k] = v
+		}
+	}
+	return node
+}
+
+func parsePostgresPlan(raw string) (sqlPlanNode, error) {
+	var plans []map[string]any
+	if err := json.Unmarshal([]byte(raw), &plans); err != nil {
+		return sqlPlanNode{}, errors.Wrap(err, "parse plan")
+	}
+	if len(plans) == 0 {
+		return sqlPlanNode{}, errors.New("empty plan")
+	}
+
+	plan, _ := plans[0]["Plan"].(map[string]any)
+	node := convertPostgresPlan(plan)
+	for k, v := range plans[0] {
+		if k != "Plan" {
+			node.Extra[k] = v
+		}
+	}
+	return node, nil
+}
+
+// convertMysqlPlan converts json plan object, node type is name of key object is stored under
+func convertMysqlPlan(typ string, obj map[string]any) sqlPlanNode {
+	node := newPlanNode(typ)
+	if name, ok := obj["table_name"].(string); ok {
+		node.Detail = name
+		if access, ok := obj["access_type"].(string); ok {
+			node.Detail += " " + access
+		}
+	}
+	if costInfo, ok := obj["cost_info"].(map[string]any); ok {
+		node.Cost = toFloat(costInfo["query_cost"])
+		if node.Cost == nil {
+			node.Cost = toFloat(costInfo["prefix_cost"])
+		}
+		node.Extra["cost_info"] = costInfo
+	}
+	node.Rows = toFloat(obj["rows_produced_per_join"])
+	if node.Rows == nil {
+		node.Rows = toFloat(obj["rows_examined_per_scan"])
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		switch v := obj[k].(type) {
+		case map[string]any:
+			if k != "cost_info" {
+				node.Children = append(node.Children, convertMysqlPlan(k, v))
+			}
+		case []any:
+			isObjects := len(v) > 0
+			for _, item := range v {
+				if item, ok := item.(map[string]any); ok {
+					typ := k
+					// NOTE: array items are usually wrappers like {"table": {...}}
+					if len(item) == 1 {
+						for kk, vv := range item {
+							if vv, ok := vv.(map[string]any); ok {
+								item, typ = vv, kk
+							}
+						}
+					}
+					node.Children = append(node.Children, convertMysqlPlan(typ, item))
+				} else {
+					isObjects = false
+				}
+			}
+			if !isObjects {
+				node.Extra[k] = v
+			}
+		default:
+			node.Extra[k] = v
+		}
+	}
+	return node
+}
+
+func parseMysqlPlan(raw string) (sqlPlanNode, error) {
+	var plan map[string]any
+	if err := json.Unmarshal([]byte(raw), &plan); err != nil {
+		return sqlPlanNode{}, errors.Wrap(err, "parse plan")
+	}
+
+	if block, ok := plan["query_block"].(map[string]any); ok {
+		return convertMysqlPlan("query_block", block), nil
+	}
+	return convertMysqlPlan("plan", plan), nil
+}
+
+// parseSqlitePlan builds tree from EXPLAIN QUERY PLAN rows: id, parent, notused, detail
+func parseSqlitePlan(rows [][]string) (sqlPlanNode, error) {
+	type item struct {
+		id, parent string
+		node       sqlPlanNode
+	}
+
+	items := make([]item, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 4 {
+			return sqlPlanNode{}, errors.Errorf("unexpected plan row %v", row)
+		}
+
+		detail := row[3]
+		typ, _, _ := strings.Cut(detail, " ")
+		node := newPlanNode(typ)
+		node.Detail = detail
+		items = append(items, item{row[0], row[1], node})
+	}
+
+	var build func(parent string) []sqlPlanNode
+	build = func(parent string) []sqlPlanNode {
+		res := []sqlPlanNode{}
+		for _, it := range items {
+			if it.parent == parent && it.id != parent {
+				it.node.Children = build(it.id)
+				res = append(res, it.node)
+			}
+		}
+		return res
+	}
+
+	root := newPlanNode("QUERY PLAN")
+	root.Children = build("0")
+	return root, nil
+}
+
+// parseClickhousePipeline builds tree from indented EXPLAIN PIPELINE lines
+func parseClickhousePipeline(lines []string) sqlPlanNode {
+	type frame struct {
+		depth int
+		node  *sqlPlanNode
+	}
+
+	root := newPlanNode("Pipeline")
+	stack := []frame{{-1, &root}}
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			continue
+		}
+
+		depth := len(line) - len(trimmed)
+		typ, detail, _ := strings.Cut(strings.TrimSpace(trimmed), " ")
+		node := newPlanNode(strings.Trim(typ, "()"))
+		node.Detail = strings.TrimSpace(detail)
+
+		for stack[len(stack)-1].depth >= depth {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].node
+		parent.Children = append(parent.Children, node)
+		stack = append(stack, frame{depth, &parent.Children[len(parent.Children)-1]})
+	}
+	return root
+}
+
+func explainQuery(db database.Database, query string, analyze bool) (string, error) {
+	switch db {
+	case database.Postgres:
+		if analyze {
+			return "EXPLAIN (ANALYZE, FORMAT JSON) " + query, nil
+		}
+		return "EXPLAIN (FORMAT JSON) " + query, nil
+	case database.MySQL:
+		return "EXPLAIN FORMAT=JSON " + query, nil
+	case database.SQLite:
+		return "EXPLAIN QUERY PLAN " + query, nil
+	case database.Clickhouse:
+		return "EXPLAIN PIPELINE " + query, nil
+	default:
+		return "", errors.Errorf("unsupported database: %s", db)
+	}
+}
+
+// explainSQL runs statements before last one and explains the last one. Caller is responsible for
+// running it in transaction which is rolled back. Clickhouse and mysql scripts may only have read only statements
+// before the last one: clickhouse has no transactions and mysql commits DDL implicitly.
+func explainSQL(ctx context.Context, conn sqlConn, req database.SQLRequest, analyze bool) (SQLExplainResponse, error) {
+	statements := splitStatements(req.Query)
+	if len(statements) == 0 {
+		return SQLExplainResponse{}, errors.New("empty query")
+	}
+
+	binder, err := newSQLBinder(req.Database, req.Params)
+	if err != nil {
+		return SQLExplainResponse{}, err
+	}
+	if req.Database == database.Clickhouse && len(req.Params) > 0 {
+		if ctx, err = withClickhouseParams(ctx, req.Params); err != nil {
+			return SQLExplainResponse{}, err
+		}
+	}
+
+	query := statements[len(statements)-1]
+	explain, err := explainQuery(req.Database, query, analyze)
+	if err != nil {
+		return SQLExplainResponse{}, err
+	}
+
+	for i, stmt := range statements[:len(statements)-1] {
+		if (req.Database == database.Clickhouse || req.Database == database.MySQL) && !isReadOnly(stmt) {
+			return SQLExplainResponse{}, errors.Errorf("statement %d may change data, %s can't roll it back", i+1, req.Database)
+		}
+	}
+
+	// NOTE: previous statements may create tables or set session settings the last one depends on
+	for i, stmt := range statements[:len(statements)-1] {
+		if _, err := conn.ExecContext(ctx, stmt, binder.args(stmt)...); err != nil {
+			return SQLExplainResponse{}, errors.Wrapf(err, "exec statement %d", i+1)
+		}
+	}
+	args := binder.args(query)
+
+	rows, err := conn.QueryContext(ctx, explain, args...)
+	if err != nil {
+		return SQLExplainResponse{}, errors.Wrap(err, "explain")
+	}
+	defer rows.Close()
+
+	var lines [][]string
+	for rows.Next() {
+		columns, err := rows.Columns()
+		if err != nil {
+			return SQLExplainResponse{}, errors.Wrap(err, "get columns")
+		}
+
+		row := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return SQLExplainResponse{}, errors.Wrap(err, "scan row")
+		}
+
+		line := make([]string, len(row))
+		for i, v := range row {
+			line[i] = v.String
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return SQLExplainResponse{}, errors.Wrap(err, "iterate rows")
+	}
+
+	raw := make([]string, len(lines))
+	for i, line := range lines {
+		raw[i] = strings.Join(line, "\t")
+	}
+	response := SQLExplainResponse{
+		Query: query,
+		Raw:   strings.Join(raw, "\n"),
+	}
+
+	switch req.Database {
+	case database.Postgres:
+		response.Plan, err = parsePostgresPlan(response.Raw)
+	case database.MySQL:
+		response.Plan, err = parseMysqlPlan(response.Raw)
+	case database.SQLite:
+		response.Plan, err = parseSqlitePlan(lines)
+	case database.Clickhouse:
+		response.Plan = parseClickhousePipeline(raw)
+	}
+	if err != nil {
+		return SQLExplainResponse{}, err
+	}
+	return response, nil
+}
+
+// _sqlExplainSavepoint is savepoint explain is rolled back to inside open transaction
+const _sqlExplainSavepoint = "impulse_explain"
+
+// SQLExplain returns query plan of last statement of request query. Previous statements are run before it,
+// all inside transaction which is rolled back, or inside open transaction of request up to savepoint.
+func (a *App) SQLExplain(requestID string, analyze bool) (SQLExplainResponse, error) {
+	req, err := a.getSQLRequest(requestID)
+	if err != nil {
+		return SQLExplainResponse{}, err
+	}
+
+	if session, ok := a.getSQLSession(database.RequestID(requestID)); ok {
+		if req.Database != session.database || req.DSN != session.dsn {
+			return SQLExplainResponse{}, errors.New("transaction is open on another database, commit or rollback it first")
+		}
+
+		session.mu.Lock()
+		defer session.mu.Unlock()
+
+		if _, err := session.tx.ExecContext(a.ctx, "SAVEPOINT "+_sqlExplainSavepoint); err != nil {
+			return SQLExplainResponse{}, errors.Wrap(err, "create savepoint")
+		}
+		defer func() {
+			for _, stmt := range []string{"ROLLBACK TO SAVEPOINT ", "RELEASE SAVEPOINT "} {
+				if _, err := session.tx.ExecContext(a.ctx, stmt+_sqlExplainSavepoint); err != nil {
+					log.Error().Err(err).Str("request_id", requestID).Msg("rollback explain savepoint")
+					return
+				}
+			}
+		}()
+		return explainSQL(a.ctx, session.tx, req, analyze)
+	}
+
+	db, err := a.openSQL(req)
+	if err != nil {
+		return SQLExplainResponse{}, errors.Wrap(err, "connect to database")
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(a.ctx)
+	if err != nil {
+		return SQLExplainResponse{}, errors.Wrap(err, "get connection")
+	}
+	defer conn.Close()
+
+	if req.Database == database.Clickhouse {
+		return explainSQL(a.ctx, conn, req, analyze)
+	}
+
+	// NOTE: EXPLAIN ANALYZE and previous statements change data, so everything is rolled back
+	tx, err := conn.BeginTx(a.ctx, nil)
+	if err != nil {
+		return SQLExplainResponse{}, errors.Wrap(err, "begin transaction")
+	}
+	defer tx.Rollback()
+
+	return explainSQL(a.ctx, tx, req, analyze)
+}
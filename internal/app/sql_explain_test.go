@@ -0,0 +1,243 @@
+package app
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+func TestExplainSQLRefusesChanges(t *testing.T) {
+	for _, tc := range []struct {
+		db    database.Database
+		query string
+	}{
+		{database.MySQL, "CREATE TABLE t (id int); SELECT * FROM t"},
+		{database.MySQL, "DROP TABLE t; SELECT 1"},
+		{database.MySQL, "INSERT INTO t VALUES (1); SELECT * FROM t"},
+		{database.Clickhouse, "TRUNCATE TABLE t; SELECT * FROM t"},
+	} {
+		t.Run(string(tc.db)+" "+tc.query, func(t *testing.T) {
+			// NOTE: nil connection fails test if any statement is run
+			_, err := explainSQL(context.Background(), nil, database.SQLRequest{Database: tc.db, Query: tc.query}, false)
+			if err == nil || !strings.Contains(err.Error(), "can't roll it back") {
+				t.Fatalf("expected refusal, got %v", err)
+			}
+		})
+	}
+}
+
+// planTree formats plan as indented lines of node type, detail and estimates
+func planTree(node sqlPlanNode) string {
+	var sb strings.Builder
+	var write func(node sqlPlanNode, depth int)
+	write = func(node sqlPlanNode, depth int) {
+		sb.WriteString(strings.Repeat("  ", depth) + node.Type)
+		if node.Detail != "" {
+			sb.WriteString(" [" + node.Detail + "]")
+		}
+		for _, v := range []struct {
+			name  string
+			value *float64
+		}{
+			{"cost", node.Cost},
+			{"rows", node.Rows},
+			{"actual_rows", node.ActualRows},
+			{"time", node.Time},
+		} {
+			if v.value != nil {
+				sb.WriteString(" " + v.name + "=" + strconv.FormatFloat(*v.value, 'f', -1, 64))
+			}
+		}
+		sb.WriteString("\n")
+		for _, child := range node.Children {
+			write(child, depth+1)
+		}
+	}
+	write(node, 0)
+	return sb.String()
+}
+
+func TestParsePostgresPlan(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"single node", `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "t", "Total Cost": 35.5, "Plan Rows": 2550}}]`, "" +
+			"Seq Scan [t] cost=35.5 rows=2550\n"},
+		{"nested", `[{"Plan": {
+			"Node Type": "Hash Join", "Total Cost": 10, "Plan Rows": 5,
+			"Plans": [
+				{"Node Type": "Seq Scan", "Relation Name": "a", "Total Cost": 1, "Plan Rows": 1},
+				{"Node Type": "Hash", "Total Cost": 2, "Plan Rows": 3, "Plans": [
+					{"Node Type": "Index Scan", "Relation Name": "b", "Index Name": "b_pkey", "Total Cost": 2, "Plan Rows": 3}
+				]}
+			]
+		}}]`, "" +
+			"Hash Join cost=10 rows=5\n" +
+			"  Seq Scan [a] cost=1 rows=1\n" +
+			"  Hash cost=2 rows=3\n" +
+			"    Index Scan [b b_pkey] cost=2 rows=3\n"},
+		{"analyzed", `[{"Plan": {"Node Type": "Function Scan", "Function Name": "generate_series", "Total Cost": 10, "Plan Rows": 1000, "Actual Rows": 3, "Actual Total Time": 0.05}, "Execution Time": 0.1}]`, "" +
+			"Function Scan [generate_series] cost=10 rows=1000 actual_rows=3 time=0.05\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			node, err := parsePostgresPlan(tc.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := planTree(node); got != tc.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+
+	for _, raw := range []string{``, `{}`, `[]`} {
+		if _, err := parsePostgresPlan(raw); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestParsePostgresPlanExtra(t *testing.T) {
+	node, err := parsePostgresPlan(`[{"Plan": {"Node Type": "Seq Scan", "Filter": "(id > 1)"}, "Planning Time": 0.1}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Extra["Filter"] != "(id > 1)" || node.Extra["Planning Time"] != 0.1 {
+		t.Errorf("got extra %v, want Filter of node and Planning Time of plan", node.Extra)
+	}
+}
+
+func TestParseMysqlPlan(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"single table", `{"query_block": {"select_id": 1, "cost_info": {"query_cost": "1.25"},
+			"table": {"table_name": "t", "access_type": "ALL", "rows_examined_per_scan": 10, "cost_info": {"prefix_cost": "1.25"}}
+		}}`, "" +
+			"query_block cost=1.25\n" +
+			"  table [t ALL] cost=1.25 rows=10\n"},
+		{"nested loop", `{"query_block": {"select_id": 1, "cost_info": {"query_cost": "3.5"},
+			"nested_loop": [
+				{"table": {"table_name": "a", "access_type": "ALL", "rows_produced_per_join": 2}},
+				{"table": {"table_name": "b", "access_type": "eq_ref", "rows_produced_per_join": 2, "rows_examined_per_scan": 1}}
+			]
+		}}`, "" +
+			"query_block cost=3.5\n" +
+			"  table [a ALL] rows=2\n" +
+			"  table [b eq_ref] rows=2\n"},
+		{"ordering", `{"query_block": {"ordering_operation": {"using_filesort": true, "table": {"table_name": "t", "access_type": "index"}}}}`, "" +
+			"query_block\n" +
+			"  ordering_operation\n" +
+			"    table [t index]\n"},
+		{"without query block", `{"message": "no tables used"}`, "" +
+			"plan\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			node, err := parseMysqlPlan(tc.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := planTree(node); got != tc.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := parseMysqlPlan(`[`); err == nil {
+		t.Error("expected error for invalid json")
+	}
+}
+
+func TestParseSqlitePlan(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rows [][]string
+		want string
+	}{
+		{"empty", nil, "" +
+			"QUERY PLAN\n"},
+		{"flat", [][]string{
+			{"2", "0", "0", "SCAN t"},
+			{"5", "0", "0", "USE TEMP B-TREE FOR ORDER BY"},
+		}, "" +
+			"QUERY PLAN\n" +
+			"  SCAN [SCAN t]\n" +
+			"  USE [USE TEMP B-TREE FOR ORDER BY]\n"},
+		{"nested", [][]string{
+			{"1", "0", "0", "COMPOUND QUERY"},
+			{"2", "1", "0", "LEFT-MOST SUBQUERY"},
+			{"5", "2", "0", "SCAN a"},
+			{"8", "1", "0", "UNION ALL"},
+			{"11", "8", "0", "SCAN b"},
+		}, "" +
+			"QUERY PLAN\n" +
+			"  COMPOUND [COMPOUND QUERY]\n" +
+			"    LEFT-MOST [LEFT-MOST SUBQUERY]\n" +
+			"      SCAN [SCAN a]\n" +
+			"    UNION [UNION ALL]\n" +
+			"      SCAN [SCAN b]\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			node, err := parseSqlitePlan(tc.rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := planTree(node); got != tc.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := parseSqlitePlan([][]string{{"2", "0", "SCAN t"}}); err == nil {
+		t.Error("expected error for short row")
+	}
+}
+
+func TestParseClickhousePipeline(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"empty", nil, "" +
+			"Pipeline\n"},
+		{"nested", []string{
+			"(Expression)",
+			"ExpressionTransform",
+			"  (ReadFromStorage)",
+			"  NullSource 0 → 1",
+			"",
+			"(Union)",
+		}, "" +
+			"Pipeline\n" +
+			"  Expression\n" +
+			"  ExpressionTransform\n" +
+			"    ReadFromStorage\n" +
+			"    NullSource [0 → 1]\n" +
+			"  Union\n"},
+		{"dedent several levels", []string{
+			"a",
+			"  b",
+			"    c",
+			"d",
+		}, "" +
+			"Pipeline\n" +
+			"  a\n" +
+			"    b\n" +
+			"      c\n" +
+			"  d\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := planTree(parseClickhousePipeline(tc.lines)); got != tc.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
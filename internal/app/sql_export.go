@@ -0,0 +1,467 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// rowWriter writes result table row by row, so that results can be streamed from cursor
+type rowWriter interface {
+	header(columns []string) error
+	row(values []any) error
+	flush() error
+}
+
+// exportValue normalizes scanned value for text formats
+func exportValue(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return "0x" + hex.EncodeToString(v)
+	case string: // NOTE: binary values from history are decoded into strings
+		if !utf8.ValidString(v) {
+			return "0x" + hex.EncodeToString([]byte(v))
+		}
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+func exportString(v any) string {
+	switch v := exportValue(v).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (w csvRowWriter) header(columns []string) error {
+	return w.w.Write(columns)
+}
+
+func (w csvRowWriter) row(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = exportString(v)
+	}
+	return w.w.Write(record)
+}
+
+func (w csvRowWriter) flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonlRowWriter struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+func (w *jsonlRowWriter) header(columns []string) error {
+	w.columns = columns
+	return nil
+}
+
+func (w *jsonlRowWriter) row(values []any) error {
+	// NOTE: write object by hand to preserve columns order
+	if err := w.w.WriteByte('{'); err != nil {
+		return err
+	}
+	for i, v := range values {
+		if i > 0 {
+			w.w.WriteByte(',')
+		}
+		key, err := json.Marshal(w.columns[i])
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(exportValue(v))
+		if err != nil {
+			return err
+		}
+		w.w.Write(key)
+		w.w.WriteByte(':')
+		w.w.Write(value)
+	}
+	_, err := w.w.WriteString("}\n")
+	return err
+}
+
+func (w *jsonlRowWriter) flush() error {
+	return w.w.Flush()
+}
+
+type markdownRowWriter struct {
+	w *bufio.Writer
+}
+
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>").Replace(s)
+}
+
+func (w markdownRowWriter) line(cells []string) error {
+	_, err := w.w.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	return err
+}
+
+func (w markdownRowWriter) header(columns []string) error {
+	cells := make([]string, len(columns))
+	separators := make([]string, len(columns))
+	for i, column := range columns {
+		cells[i] = markdownCell(column)
+		separators[i] = "---"
+	}
+	if err := w.line(cells); err != nil {
+		return err
+	}
+	return w.line(separators)
+}
+
+func (w markdownRowWriter) row(values []any) error {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		if v == nil {
+			cells[i] = "NULL"
+		} else {
+			cells[i] = markdownCell(exportString(v))
+		}
+	}
+	return w.line(cells)
+}
+
+func (w markdownRowWriter) flush() error {
+	return w.w.Flush()
+}
+
+type insertRowWriter struct {
+	w       *bufio.Writer
+	db      database.Database
+	table   string
+	columns string
+}
+
+func quoteIdent(db database.Database, name string) string {
+	switch db {
+	case database.MySQL, database.Clickhouse:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+func quoteString(db database.Database, s string) string {
+	s = strings.ReplaceAll(s, "'", "''")
+	if db == database.MySQL || db == database.Clickhouse {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + s + "'"
+}
+
+// sqlLiteral formats value as literal of given dialect
+func sqlLiteral(db database.Database, v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if db == database.SQLite || db == database.MySQL {
+			return map[bool]string{true: "1", false: "0"}[v]
+		}
+		return strings.ToUpper(strconv.FormatBool(v))
+	case int64, int32, int16, int8, int, uint64, uint32, uint16, uint8, uint:
+		return fmt.Sprint(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case time.Time:
+		return quoteString(db, v.Format("2006-01-02 15:04:05.999999"))
+	case []byte:
+		if utf8.Valid(v) {
+			return quoteString(db, string(v))
+		}
+		switch db {
+		case database.Postgres:
+			return `'\x` + hex.EncodeToString(v) + `'::bytea`
+		case database.Clickhouse:
+			return "unhex('" + hex.EncodeToString(v) + "')"
+		default:
+			return "X'" + hex.EncodeToString(v) + "'"
+		}
+	case string:
+		if !utf8.ValidString(v) {
+			return sqlLiteral(db, []byte(v))
+		}
+		return quoteString(db, v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return quoteString(db, fmt.Sprint(v))
+		}
+		return quoteString(db, string(b))
+	}
+}
+
+func (w *insertRowWriter) header(columns []string) error {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdent(w.db, column)
+	}
+	w.columns = strings.Join(quoted, ", ")
+	return nil
+}
+
+func (w *insertRowWriter) row(values []any) error {
+	literals := make([]string, len(values))
+	for i, v := range values {
+		literals[i] = sqlLiteral(w.db, v)
+	}
+	_, err := fmt.Fprintf(w.w, "INSERT INTO %s (%s) VALUES (%s);\n", w.table, w.columns, strings.Join(literals, ", "))
+	return err
+}
+
+func (w *insertRowWriter) flush() error {
+	return w.w.Flush()
+}
+
+var _reFromTable = regexp.MustCompile(`(?is)\bfrom\s+((?:[\w$]+|"[^"]+"|` + "`[^`]+`" + `)(?:\.(?:[\w$]+|"[^"]+"|` + "`[^`]+`" + `))?)`)
+
+// guessTable finds table name for INSERT statements from query
+func guessTable(query string) string {
+	if match := _reFromTable.FindStringSubmatch(query); match != nil {
+		return match[1]
+	}
+	return "result"
+}
+
+func newRowWriter(w io.Writer, format database.SQLExportFormat, db database.Database, query string) (rowWriter, error) {
+	switch format {
+	case database.SQLExportCSV:
+		return csvRowWriter{csv.NewWriter(w)}, nil
+	case database.SQLExportTSV:
+		cw := csv.NewWriter(w)
+		cw.Comma = '\t'
+		return csvRowWriter{cw}, nil
+	case database.SQLExportJSONL:
+		return &jsonlRowWriter{w: bufio.NewWriter(w)}, nil
+	case database.SQLExportMarkdown:
+		return markdownRowWriter{bufio.NewWriter(w)}, nil
+	case database.SQLExportInsert:
+		return &insertRowWriter{w: bufio.NewWriter(w), db: db, table: guessTable(query)}, nil
+	default:
+		return nil, errors.Errorf("unknown export format %q", format)
+	}
+}
+
+// streamSQL runs script on conn and writes rows of its last statement, previous statements are executed.
+// Statements changing data are refused, since running script again would repeat their effects.
+func streamSQL(ctx context.Context, conn sqlConn, req database.SQLRequest, w rowWriter) (int, error) {
+	statements := splitStatements(req.Query)
+	if len(statements) == 0 {
+		return 0, errors.New("empty query")
+	}
+	for i, stmt := range statements {
+		if !isReadOnly(stmt) {
+			return 0, errors.Errorf("statement %d may change data, export result from history instead", i+1)
+		}
+	}
+
+	if req.Database == database.Clickhouse && len(req.Params) > 0 {
+		var err error
+		if ctx, err = withClickhouseParams(ctx, req.Params); err != nil {
+			return 0, err
+		}
+	}
+
+	binder, err := newSQLBinder(req.Database, req.Params)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, stmt := range statements[:len(statements)-1] {
+		if _, err := conn.ExecContext(ctx, stmt, binder.args(stmt)...); err != nil {
+			return 0, errors.Wrapf(err, "exec %q", stmt)
+		}
+	}
+
+	query := statements[len(statements)-1]
+	rows, err := conn.QueryContext(ctx, query, binder.args(query)...)
+	if err != nil {
+		return 0, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, errors.Wrap(err, "get columns")
+	}
+	if err := w.header(columns); err != nil {
+		return 0, errors.Wrap(err, "write header")
+	}
+
+	n := 0
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, errors.Wrap(err, "scan row")
+		}
+		if err := w.row(values); err != nil {
+			return n, errors.Wrap(err, "write row")
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, errors.Wrap(err, "iterate rows")
+	}
+	return n, nil
+}
+
+func (a *App) exportFresh(id database.RequestID, req database.SQLRequest, w rowWriter) (int, error) {
+	if session, ok := a.getSQLSession(id); ok {
+		if req.Database != session.database || req.DSN != session.dsn {
+			return 0, errors.New("transaction is open on another database, commit or rollback it first")
+		}
+
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		return streamSQL(a.ctx, session.tx, req, w)
+	}
+
+	db, err := a.openSQL(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "connect to database")
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(a.ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "get connection")
+	}
+	defer conn.Close()
+
+	return streamSQL(a.ctx, conn, req, w)
+}
+
+// ExportSQLResult writes result of request to file at path in given format.
+// If historyIndex is negative, query is run again and rows are streamed from cursor,
+// otherwise result stored in history entry is written, 0 being the latest one.
+// File is replaced only if export succeeds. Number of written rows is returned.
+func (a *App) ExportSQLResult(
+	requestID string,
+	historyIndex int,
+	format database.SQLExportFormat,
+	path string,
+) (int, error) {
+	known := false
+	for _, f := range database.AllSQLExportFormats {
+		known = known || f.Value == format
+	}
+	if !known {
+		return 0, errors.Errorf("unknown export format %q", format)
+	}
+
+	request, err := database.Get(a.ctx, a.DB, database.RequestID(requestID))
+	if err != nil {
+		return 0, errors.Wrapf(err, "get request id=%q", requestID)
+	}
+	req, ok := request.Data.(database.SQLRequest)
+	if !ok {
+		return 0, errors.Errorf("query kind is %s, expected sql", request.Data.Kind())
+	}
+
+	var resp database.SQLResponse
+	if historyIndex >= 0 {
+		entry, err := historyAt(request, historyIndex)
+		if err != nil {
+			return 0, err
+		}
+		if req, ok = entry.Request.(database.SQLRequest); !ok {
+			return 0, errors.Errorf("history entry %d has %T request, expected sql", historyIndex, entry.Request)
+		}
+		if resp, ok = entry.Response.(database.SQLResponse); !ok {
+			return 0, errors.Errorf("history entry %d has %T response, expected sql", historyIndex, entry.Response)
+		}
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return 0, errors.Wrap(err, "create file")
+	}
+	defer os.Remove(f.Name()) // NOTE: no-op after successful rename
+	defer f.Close()
+
+	w, err := newRowWriter(f, format, req.Database, req.Query)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	if historyIndex < 0 {
+		if n, err = a.exportFresh(database.RequestID(requestID), req, w); err != nil {
+			return n, err
+		}
+	} else {
+		if err := w.header(resp.Columns); err != nil {
+			return 0, errors.Wrap(err, "write header")
+		}
+		for _, row := range resp.Rows {
+			if err := w.row(row); err != nil {
+				return n, errors.Wrap(err, "write row")
+			}
+			n++
+		}
+	}
+
+	if err := w.flush(); err != nil {
+		return n, errors.Wrap(err, "flush")
+	}
+
+	// NOTE: temp file is private, exported one gets mode of replaced file or usual one
+	mode := os.FileMode(0o644)
+	if stat, err := os.Stat(path); err == nil {
+		mode = stat.Mode().Perm()
+	}
+	if err := f.Chmod(mode); err != nil {
+		return n, errors.Wrap(err, "set file mode")
+	}
+	if err := f.Close(); err != nil {
+		return n, errors.Wrap(err, "close file")
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return n, errors.Wrap(err, "replace file")
+	}
+	return n, nil
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+func TestExportSQLResultFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix file modes")
+	}
+
+	dir := t.TempDir()
+	a, start, stop := New(afero.NewMemMapFs())
+	start(context.Background())
+	defer stop()
+
+	if _, err := a.Create("q", database.KindSQL); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Update("q", database.KindSQL, map[string]any{
+		"dsn":      filepath.Join(dir, "db.sqlite"),
+		"database": database.SQLite,
+		"query":    "SELECT 1 AS a",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Perform("q"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		existing os.FileMode // NOTE: mode of file being replaced, zero if there is no file
+		want     os.FileMode
+	}{
+		{"new file", 0, 0o644},
+		{"replaced private file", 0o600, 0o600},
+		{"replaced shared file", 0o664, 0o664},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "export.csv")
+			if tc.existing != 0 {
+				if err := os.WriteFile(path, nil, tc.existing); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, tc.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := a.ExportSQLResult("q", 0, database.SQLExportCSV, path); err != nil {
+				t.Fatal(err)
+			}
+			stat, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode := stat.Mode().Perm(); mode != tc.want {
+				t.Fatalf("mode is %o, want %o", mode, tc.want)
+			}
+		})
+	}
+}
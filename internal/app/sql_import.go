@@ -0,0 +1,684 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+type SQLImportColumn struct {
+	// Name is column name in file
+	Name string `json:"name"`
+	// Target is column name in table, same as Name if empty
+	Target string `json:"target"`
+	// Type is inferred from values if empty
+	Type database.SQLParamType `json:"type"`
+}
+
+type SQLImportOptions struct {
+	// Path is path to .csv, .tsv, .jsonl or .ndjson file
+	Path  string `json:"path"`
+	Table string `json:"table"`
+	// Create creates table if it does not exist
+	Create  bool              `json:"create"`
+	Columns []SQLImportColumn `json:"columns"`
+	// EmptyStrings imports empty csv fields as empty strings instead of NULLs.
+	// Empty fields of non string columns are NULLs anyway.
+	EmptyStrings bool `json:"empty_strings"`
+}
+
+type sqlRejectedLine struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type SQLImportResponse struct {
+	Columns []SQLImportColumn `json:"columns"`
+	Loaded  int               `json:"loaded"`
+	// Rejected holds first rejected lines, RejectedCount is total number of them
+	Rejected      []sqlRejectedLine `json:"rejected"`
+	RejectedCount int               `json:"rejected_count"`
+}
+
+const maxRejectedLines = 100
+
+// importRecord is row of imported file, values are strings or nils for NULLs
+type importRecord struct {
+	line   int
+	values []any
+}
+
+// readImportCSV calls fn for each record read and returns header and lines which can't be parsed
+func readImportCSV(r io.Reader, comma rune, emptyStrings bool, fn func(importRecord) error) ([]string, []sqlRejectedLine, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "read header")
+	}
+	header = slices.Clone(header)
+
+	var rejected []sqlRejectedLine
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, errors.Wrap(err, "read record")
+			}
+			rejected = append(rejected, sqlRejectedLine{parseErr.StartLine, parseErr.Err.Error()})
+			continue
+		}
+
+		line, _ := cr.FieldPos(0)
+		if len(record) != len(header) {
+			rejected = append(rejected, sqlRejectedLine{line, "expected " + strconv.Itoa(len(header)) + " fields, got " + strconv.Itoa(len(record))})
+			continue
+		}
+
+		values := make([]any, len(record))
+		for i, v := range record {
+			if v != "" || emptyStrings {
+				values[i] = v
+			}
+		}
+		if err := fn(importRecord{line, values}); err != nil {
+			return nil, nil, err
+		}
+	}
+	return header, rejected, nil
+}
+
+// parseJSONObject parses json object preserving keys order, values are converted to import values
+func parseJSONObject(b []byte) ([]string, []any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, errors.New("expected json object")
+	}
+
+	var keys []string
+	var values []any
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+
+		var value any
+		switch raw := bytes.TrimSpace(raw); {
+		case string(raw) == "null":
+		case raw[0] == '"':
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, nil, err
+			}
+			value = s
+		default: // NOTE: numbers and bools are kept as is, objects and arrays are imported as json text
+			value = string(raw)
+		}
+
+		keys = append(keys, tok.(string))
+		values = append(values, value)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	if dec.More() {
+		return nil, nil, errors.New("unexpected data after object")
+	}
+	return keys, values, nil
+}
+
+// readImportJSONL calls fn for each object read and returns columns and lines which can't be parsed.
+// Columns are union of keys of all objects, so values of record are in order of columns, but record
+// misses trailing columns first appeared in later lines.
+func readImportJSONL(r io.Reader, fn func(importRecord) error) ([]string, []sqlRejectedLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	var columns []string
+	index := map[string]int{}
+	var rejected []sqlRejectedLine
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		keys, values, err := parseJSONObject(b)
+		if err != nil {
+			rejected = append(rejected, sqlRejectedLine{line, err.Error()})
+			continue
+		}
+
+		record := make([]any, len(columns), max(len(columns), len(keys)))
+		for i, key := range keys {
+			j, ok := index[key]
+			if !ok {
+				j = len(columns)
+				index[key] = j
+				columns = append(columns, key)
+				record = append(record, nil)
+			}
+			record[j] = values[i]
+		}
+		if err := fn(importRecord{line, record}); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, "read lines")
+	}
+	return columns, rejected, nil
+}
+
+// readImportFile streams records of file to fn, so file is never loaded into memory as whole
+func readImportFile(path string, emptyStrings bool, fn func(importRecord) error) ([]string, []sqlRejectedLine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "open file")
+	}
+	defer f.Close()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return readImportCSV(f, ',', emptyStrings, fn)
+	case ".tsv":
+		return readImportCSV(f, '\t', emptyStrings, fn)
+	case ".jsonl", ".ndjson":
+		return readImportJSONL(f, fn)
+	default:
+		return nil, nil, errors.Errorf("unsupported file extension %q, expected .csv, .tsv, .jsonl or .ndjson", ext)
+	}
+}
+
+var _importTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func parseImportTime(s string) (time.Time, error) {
+	for _, layout := range _importTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("invalid time %q", s)
+}
+
+func guessImportType(s string) database.SQLParamType {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return database.SQLParamTypeInt
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return database.SQLParamTypeFloat
+	}
+	if strings.EqualFold(s, "true") || strings.EqualFold(s, "false") {
+		return database.SQLParamTypeBool
+	}
+	if _, err := parseImportTime(s); err == nil {
+		return database.SQLParamTypeTime
+	}
+	return database.SQLParamTypeString
+}
+
+// inferImportType returns narrowest type both values of column inferred so far as typ and value can be
+// converted to. Empty typ means no values yet, columns without values are strings.
+func inferImportType(typ database.SQLParamType, v any) database.SQLParamType {
+	s, ok := v.(string)
+	if !ok || s == "" || typ == database.SQLParamTypeString { // NOTE: empty strings are NULLs in non string columns
+		return typ
+	}
+
+	switch t := guessImportType(s); {
+	case typ == "" || typ == t:
+		return t
+	case typ == database.SQLParamTypeInt && t == database.SQLParamTypeFloat,
+		typ == database.SQLParamTypeFloat && t == database.SQLParamTypeInt:
+		return database.SQLParamTypeFloat
+	default:
+		return database.SQLParamTypeString
+	}
+}
+
+func convertImportValue(typ database.SQLParamType, s string) (any, error) {
+	switch typ {
+	case database.SQLParamTypeString:
+		return s, nil
+	case database.SQLParamTypeInt:
+		return strconv.ParseInt(s, 10, 64)
+	case database.SQLParamTypeFloat:
+		return strconv.ParseFloat(s, 64)
+	case database.SQLParamTypeBool:
+		return strconv.ParseBool(s)
+	case database.SQLParamTypeTime:
+		return parseImportTime(s)
+	default:
+		return nil, errors.Errorf("unsupported column type %q", typ)
+	}
+}
+
+func importColumnType(db database.Database, typ database.SQLParamType) string {
+	types := map[database.Database]map[database.SQLParamType]string{
+		database.Postgres: {
+			database.SQLParamTypeInt:    "bigint",
+			database.SQLParamTypeFloat:  "double precision",
+			database.SQLParamTypeBool:   "boolean",
+			database.SQLParamTypeTime:   "timestamptz",
+			database.SQLParamTypeString: "text",
+		},
+		database.MySQL: {
+			database.SQLParamTypeInt:    "BIGINT",
+			database.SQLParamTypeFloat:  "DOUBLE",
+			database.SQLParamTypeBool:   "BOOLEAN",
+			database.SQLParamTypeTime:   "DATETIME(6)",
+			database.SQLParamTypeString: "TEXT",
+		},
+		database.SQLite: {
+			database.SQLParamTypeInt:    "INTEGER",
+			database.SQLParamTypeFloat:  "REAL",
+			database.SQLParamTypeBool:   "BOOLEAN",
+			database.SQLParamTypeTime:   "DATETIME",
+			database.SQLParamTypeString: "TEXT",
+		},
+		database.Clickhouse: {
+			database.SQLParamTypeInt:    "Nullable(Int64)",
+			database.SQLParamTypeFloat:  "Nullable(Float64)",
+			database.SQLParamTypeBool:   "Nullable(Bool)",
+			database.SQLParamTypeTime:   "Nullable(DateTime64(6))",
+			database.SQLParamTypeString: "Nullable(String)",
+		},
+	}
+	return types[db][typ]
+}
+
+// quoteTable quotes possibly schema qualified table name
+func quoteTable(db database.Database, table string) string {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdent(db, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func createImportTable(ctx context.Context, db *sql.DB, dialect database.Database, table string, columns []SQLImportColumn) error {
+	defs := make([]string, len(columns))
+	for i, column := range columns {
+		defs[i] = quoteIdent(dialect, column.Target) + " " + importColumnType(dialect, column.Type)
+	}
+
+	query := "CREATE TABLE IF NOT EXISTS " + quoteTable(dialect, table) + " (" + strings.Join(defs, ", ") + ")"
+	if dialect == database.Clickhouse {
+		query += " ENGINE = MergeTree ORDER BY tuple()"
+	}
+	_, err := db.ExecContext(ctx, query)
+	return err
+}
+
+// rowInserter inserts imported rows one by one, so that rows are streamed from file.
+// Rows are written to database on flush at the latest, close rolls back rows which are not flushed.
+type rowInserter interface {
+	row(values []any) error
+	flush() error
+	close()
+}
+
+// preparedInserter inserts rows using statement prepared in transaction, transaction is committed every batch rows,
+// zero batch means single transaction. On failure error tells how many rows were committed by previous batches.
+type preparedInserter struct {
+	ctx       context.Context
+	db        *sql.DB
+	query     string
+	batch     int
+	tx        *sql.Tx
+	stmt      *sql.Stmt
+	n         int
+	committed int
+}
+
+func (w *preparedInserter) wrap(err error) error {
+	if w.committed > 0 {
+		return errors.Wrapf(err, "%d rows were committed before failure", w.committed)
+	}
+	return err
+}
+
+func (w *preparedInserter) row(values []any) error {
+	if w.tx == nil {
+		tx, err := w.db.BeginTx(w.ctx, nil)
+		if err != nil {
+			return w.wrap(errors.Wrap(err, "begin transaction"))
+		}
+
+		stmt, err := tx.PrepareContext(w.ctx, w.query)
+		if err != nil {
+			tx.Rollback()
+			return w.wrap(errors.Wrap(err, "prepare insert"))
+		}
+		w.tx, w.stmt = tx, stmt
+	}
+
+	if _, err := w.stmt.ExecContext(w.ctx, values...); err != nil {
+		return w.wrap(errors.Wrap(err, "insert row"))
+	}
+	w.n++
+	if w.n == w.batch {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *preparedInserter) flush() error {
+	if w.tx == nil {
+		return nil
+	}
+
+	err := w.tx.Commit()
+	w.stmt.Close()
+	w.tx, w.stmt = nil, nil
+	if err != nil {
+		return w.wrap(err)
+	}
+	w.committed += w.n
+	w.n = 0
+	return nil
+}
+
+func (w *preparedInserter) close() {
+	if w.tx != nil {
+		w.stmt.Close()
+		w.tx.Rollback()
+	}
+}
+
+// sqliteInserter stores times in format understood by sqlite date functions
+type sqliteInserter struct {
+	*preparedInserter
+}
+
+func (w sqliteInserter) row(values []any) error {
+	for i, v := range values {
+		if t, ok := v.(time.Time); ok {
+			values[i] = t.Format("2006-01-02 15:04:05.999999999-07:00")
+		}
+	}
+	return w.preparedInserter.row(values)
+}
+
+// postgresInserter copies rows in single transaction
+type postgresInserter struct {
+	ctx  context.Context
+	tx   *sql.Tx
+	stmt *sql.Stmt
+}
+
+func newPostgresInserter(ctx context.Context, db *sql.DB, table string, columns []string) (*postgresInserter, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "begin transaction")
+	}
+
+	query := pq.CopyIn(table, columns...)
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		query = pq.CopyInSchema(schema, name, columns...)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "prepare copy")
+	}
+	return &postgresInserter{ctx, tx, stmt}, nil
+}
+
+func (w *postgresInserter) row(values []any) error {
+	_, err := w.stmt.ExecContext(w.ctx, values...)
+	return errors.Wrap(err, "copy row")
+}
+
+func (w *postgresInserter) flush() error {
+	if _, err := w.stmt.ExecContext(w.ctx); err != nil {
+		return errors.Wrap(err, "flush copy")
+	}
+	if err := w.stmt.Close(); err != nil {
+		return errors.Wrap(err, "close copy")
+	}
+	return w.tx.Commit()
+}
+
+func (w *postgresInserter) close() {
+	w.tx.Rollback()
+}
+
+// mysqlInserter inserts rows using multi row INSERT statements in single transaction
+type mysqlInserter struct {
+	ctx          context.Context
+	tx           *sql.Tx
+	prefix       string
+	placeholders string
+	columns      int
+	batch        int
+	args         []any
+}
+
+func newMysqlInserter(ctx context.Context, db *sql.DB, table string, columns []string) (*mysqlInserter, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "begin transaction")
+	}
+
+	return &mysqlInserter{
+		ctx,
+		tx,
+		"INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES ",
+		"(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")",
+		len(columns),
+		min(1000, 65535/len(columns)), // NOTE: mysql allows at most 65535 placeholders per statement
+		nil,
+	}, nil
+}
+
+func (w *mysqlInserter) exec() error {
+	n := len(w.args) / w.columns
+	query := w.prefix + strings.TrimSuffix(strings.Repeat(w.placeholders+", ", n), ", ")
+	if _, err := w.tx.ExecContext(w.ctx, query, w.args...); err != nil {
+		return errors.Wrap(err, "insert rows")
+	}
+	w.args = w.args[:0]
+	return nil
+}
+
+func (w *mysqlInserter) row(values []any) error {
+	w.args = append(w.args, values...)
+	if len(w.args) == w.batch*w.columns {
+		return w.exec()
+	}
+	return nil
+}
+
+func (w *mysqlInserter) flush() error {
+	if len(w.args) > 0 {
+		if err := w.exec(); err != nil {
+			return err
+		}
+	}
+	return w.tx.Commit()
+}
+
+func (w *mysqlInserter) close() {
+	w.tx.Rollback()
+}
+
+func newRowInserter(ctx context.Context, db *sql.DB, dialect database.Database, table string, columns []string) (rowInserter, error) {
+	if dialect == database.Postgres {
+		return newPostgresInserter(ctx, db, table, columns)
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdent(dialect, column)
+	}
+	table = quoteTable(dialect, table)
+
+	switch dialect {
+	case database.MySQL:
+		return newMysqlInserter(ctx, db, table, quoted)
+	case database.SQLite:
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		query := "INSERT INTO " + table + " (" + strings.Join(quoted, ", ") + ") VALUES (" + placeholders + ")"
+		return sqliteInserter{&preparedInserter{ctx: ctx, db: db, query: query}}, nil
+	case database.Clickhouse:
+		// NOTE: clickhouse driver sends rows prepared in transaction as single batch on commit
+		query := "INSERT INTO " + table + " (" + strings.Join(quoted, ", ") + ")"
+		return &preparedInserter{ctx: ctx, db: db, query: query, batch: 100_000}, nil
+	default:
+		return nil, errors.Errorf("unsupported database: %s", dialect)
+	}
+}
+
+// ImportSQLTable loads rows from csv, tsv or jsonl file into table of request database.
+// Lines which can't be parsed or converted to column types are skipped and reported.
+// NOTE: file is read twice, first to find columns and infer their types, then to insert rows.
+func (a *App) ImportSQLTable(requestID string, opts SQLImportOptions) (SQLImportResponse, error) {
+	req, err := a.getSQLRequest(requestID)
+	if err != nil {
+		return SQLImportResponse{}, err
+	}
+	if opts.Table == "" {
+		return SQLImportResponse{}, errors.New("table name is required")
+	}
+
+	var types []database.SQLParamType
+	names, _, err := readImportFile(opts.Path, opts.EmptyStrings, func(record importRecord) error {
+		if n := len(record.values) - len(types); n > 0 {
+			types = append(types, make([]database.SQLParamType, n)...)
+		}
+		for i, v := range record.values {
+			types[i] = inferImportType(types[i], v)
+		}
+		return nil
+	})
+	if err != nil {
+		return SQLImportResponse{}, errors.Wrapf(err, "read %s", opts.Path)
+	}
+	if len(names) == 0 {
+		return SQLImportResponse{}, errors.New("no columns found in file")
+	}
+
+	columns := make([]SQLImportColumn, len(names))
+	targets := make([]string, len(names))
+	for i, name := range names {
+		column := SQLImportColumn{Name: name}
+		if j := slices.IndexFunc(opts.Columns, func(c SQLImportColumn) bool {
+			return c.Name == name
+		}); j != -1 {
+			column = opts.Columns[j]
+		}
+		if column.Target == "" {
+			column.Target = name
+		}
+		if column.Type == "" {
+			column.Type = database.SQLParamTypeString
+			if i < len(types) && types[i] != "" {
+				column.Type = types[i]
+			}
+		}
+		if importColumnType(req.Database, column.Type) == "" {
+			return SQLImportResponse{}, errors.Errorf("unsupported type %q of column %q", column.Type, name)
+		}
+		columns[i] = column
+		targets[i] = column.Target
+	}
+
+	db, err := a.openSQL(req)
+	if err != nil {
+		return SQLImportResponse{}, errors.Wrap(err, "connect to database")
+	}
+	defer db.Close()
+
+	if opts.Create {
+		if err := createImportTable(a.ctx, db, req.Database, opts.Table, columns); err != nil {
+			return SQLImportResponse{}, errors.Wrap(err, "create table")
+		}
+
+		a.sqlSchemasMu.Lock()
+		delete(a.sqlSchemas, sqlSchemaKey(req))
+		a.sqlSchemasMu.Unlock()
+	}
+
+	inserter, err := newRowInserter(a.ctx, db, req.Database, opts.Table, targets)
+	if err != nil {
+		return SQLImportResponse{}, errors.Wrap(err, "import rows")
+	}
+	defer inserter.close()
+
+	loaded := 0
+	var rejected []sqlRejectedLine
+	_, rejectedLines, err := readImportFile(opts.Path, opts.EmptyStrings, func(record importRecord) error {
+		if len(record.values) > len(columns) {
+			return errors.Errorf("line %d: file was changed while importing", record.line)
+		}
+
+		row := make([]any, len(columns))
+		for i, v := range record.values {
+			if s, ok := v.(string); ok && (s != "" || columns[i].Type == database.SQLParamTypeString) {
+				value, err := convertImportValue(columns[i].Type, s)
+				if err != nil {
+					rejected = append(rejected, sqlRejectedLine{record.line, errors.Wrapf(err, "column %q", columns[i].Name).Error()})
+					return nil
+				}
+				row[i] = value
+			}
+		}
+
+		if err := inserter.row(row); err != nil {
+			return errors.Wrap(err, "import rows")
+		}
+		loaded++
+		return nil
+	})
+	if err != nil {
+		return SQLImportResponse{}, errors.Wrapf(err, "import %s", opts.Path)
+	}
+	if err := inserter.flush(); err != nil {
+		return SQLImportResponse{}, errors.Wrap(err, "import rows")
+	}
+
+	rejected = append(rejected, rejectedLines...)
+	slices.SortFunc(rejected, func(a, b sqlRejectedLine) int {
+		return a.Line - b.Line
+	})
+	return SQLImportResponse{
+		Columns:       columns,
+		Loaded:        loaded,
+		Rejected:      rejected[:min(len(rejected), maxRejectedLines)],
+		RejectedCount: len(rejected),
+	}, nil
+}
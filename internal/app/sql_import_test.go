@@ -0,0 +1,177 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+func TestInferImportType(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		values []any
+		want   database.SQLParamType
+	}{
+		{"no values", nil, ""},
+		{"nulls and empty strings", []any{nil, ""}, ""},
+		{"ints", []any{"1", nil, "-2"}, database.SQLParamTypeInt},
+		{"ints and floats", []any{"1", "2.5"}, database.SQLParamTypeFloat},
+		{"floats and ints", []any{"2.5", "1"}, database.SQLParamTypeFloat},
+		{"bools", []any{"true", "FALSE"}, database.SQLParamTypeBool},
+		{"times", []any{"2024-01-02", "2024-01-02T03:04:05Z"}, database.SQLParamTypeTime},
+		{"mixed", []any{"1", "true"}, database.SQLParamTypeString},
+		{"string is final", []any{"x", "1", "2"}, database.SQLParamTypeString},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got database.SQLParamType
+			for _, v := range tc.values {
+				got = inferImportType(got, v)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestImportSQLTable(t *testing.T) {
+	dir := t.TempDir()
+	dsn := filepath.Join(dir, "db.sqlite")
+	a, start, stop := New(afero.NewMemMapFs())
+	start(context.Background())
+	defer stop()
+
+	if _, err := a.Create("q", database.KindSQL); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Update("q", database.KindSQL, map[string]any{
+		"dsn":      dsn,
+		"database": database.SQLite,
+		"query":    "SELECT 1",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, tc := range []struct {
+		name          string
+		file          string
+		data          string
+		types         []database.SQLParamType
+		rejectedLines []int
+		want          string // NOTE: rows of table as "|" separated values, one per line
+	}{
+		{
+			"csv", "data.csv",
+			"id,name,score\n1,a,1.5\n2,,2\n",
+			[]database.SQLParamType{database.SQLParamTypeInt, database.SQLParamTypeString, database.SQLParamTypeFloat},
+			nil,
+			"1|a|1.5\n2|<nil>|2",
+		},
+		{
+			"tsv", "data.tsv",
+			"id\tok\n1\ttrue\n",
+			[]database.SQLParamType{database.SQLParamTypeInt, database.SQLParamTypeBool},
+			nil,
+			"1|1",
+		},
+		{
+			"csv rejected lines", "data.csv",
+			"id,name\n1,a\n2\n3,c\n",
+			[]database.SQLParamType{database.SQLParamTypeInt, database.SQLParamTypeString},
+			[]int{3},
+			"1|a\n3|c",
+		},
+		{
+			"jsonl union of keys", "data.jsonl",
+			`{"id": 1}` + "\n\n" + `{"id": 2, "tags": [1, 2]}` + "\n" + `{"name": "x", "id": 3}` + "\n" + `not json` + "\n",
+			[]database.SQLParamType{database.SQLParamTypeInt, database.SQLParamTypeString, database.SQLParamTypeString},
+			[]int{5},
+			"1|<nil>|<nil>\n2|[1, 2]|<nil>\n3|<nil>|x",
+		},
+		{
+			"many rows", "data.csv",
+			"id\n" + strings.Repeat("1\n", 2500),
+			[]database.SQLParamType{database.SQLParamTypeInt},
+			nil,
+			strings.TrimSuffix(strings.Repeat("1\n", 2500), "\n"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			table := strings.ReplaceAll(tc.name, " ", "_")
+			resp, err := a.ImportSQLTable("q", SQLImportOptions{Path: path, Table: table, Create: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(resp.Columns) != len(tc.types) {
+				t.Fatalf("got columns %v, want types %v", resp.Columns, tc.types)
+			}
+			for i, column := range resp.Columns {
+				if column.Type != tc.types[i] {
+					t.Errorf("column %q: got type %q, want %q", column.Name, column.Type, tc.types[i])
+				}
+			}
+
+			var lines []int
+			for _, rejected := range resp.Rejected {
+				lines = append(lines, rejected.Line)
+			}
+			if fmt.Sprint(lines) != fmt.Sprint(tc.rejectedLines) || resp.RejectedCount != len(tc.rejectedLines) {
+				t.Errorf("got rejected %v, want lines %v", resp.Rejected, tc.rejectedLines)
+			}
+
+			rows, err := db.Query("SELECT * FROM " + table + " ORDER BY rowid")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var got []string
+			for rows.Next() {
+				values := make([]any, len(resp.Columns))
+				ptrs := make([]any, len(values))
+				for i := range values {
+					ptrs[i] = &values[i]
+				}
+				if err := rows.Scan(ptrs...); err != nil {
+					t.Fatal(err)
+				}
+
+				cells := make([]string, len(values))
+				for i, v := range values {
+					cells[i] = fmt.Sprint(v)
+				}
+				got = append(got, strings.Join(cells, "|"))
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != resp.Loaded {
+				t.Errorf("loaded %d rows, table has %d", resp.Loaded, len(got))
+			}
+			if got := strings.Join(got, "\n"); got != tc.want {
+				t.Errorf("got rows\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,470 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+type sqlColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+type sqlIndex struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+}
+
+type sqlForeignKey struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefSchema  string   `json:"ref_schema"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+}
+
+type sqlTable struct {
+	Name        string          `json:"name"`
+	IsView      bool            `json:"is_view"`
+	Columns     []sqlColumn     `json:"columns"`
+	Indexes     []sqlIndex      `json:"indexes"`
+	ForeignKeys []sqlForeignKey `json:"foreign_keys"`
+}
+
+type sqlSchema struct {
+	Name   string     `json:"name"`
+	Tables []sqlTable `json:"tables"`
+}
+
+type SQLSchemaResponse struct {
+	Schemas []sqlSchema `json:"schemas"`
+}
+
+// schemaBuilder collects introspection results preserving order of tables
+type schemaBuilder struct {
+	schemas []string
+	tables  map[string][]string
+	byName  map[[2]string]*sqlTable
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		tables: map[string][]string{},
+		byName: map[[2]string]*sqlTable{},
+	}
+}
+
+func (b *schemaBuilder) table(schema, name string) *sqlTable {
+	key := [2]string{schema, name}
+	if t, ok := b.byName[key]; ok {
+		return t
+	}
+
+	if _, ok := b.tables[schema]; !ok {
+		b.schemas = append(b.schemas, schema)
+	}
+	b.tables[schema] = append(b.tables[schema], name)
+	t := &sqlTable{
+		Name:        name,
+		Columns:     []sqlColumn{},
+		Indexes:     []sqlIndex{},
+		ForeignKeys: []sqlForeignKey{},
+	}
+	b.byName[key] = t
+	return t
+}
+
+func (b *schemaBuilder) build() SQLSchemaResponse {
+	schemas := make([]sqlSchema, 0, len(b.schemas))
+	for _, schema := range b.schemas {
+		tables := make([]sqlTable, 0, len(b.tables[schema]))
+		for _, name := range b.tables[schema] {
+			tables = append(tables, *b.byName[[2]string{schema, name}])
+		}
+		schemas = append(schemas, sqlSchema{schema, tables})
+	}
+	return SQLSchemaResponse{schemas}
+}
+
+// queryStrings runs introspection query and scans every column as string
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...any) ([][]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, errors.Wrap(err, "get columns")
+	}
+
+	var res [][]string
+	for rows.Next() {
+		row := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, errors.Wrap(err, "scan row")
+		}
+
+		values := make([]string, len(columns))
+		for i, v := range row {
+			values[i] = v.String
+		}
+		res = append(res, values)
+	}
+	return res, rows.Err()
+}
+
+func isTrue(s string) bool {
+	switch strings.ToLower(s) {
+	case "1", "t", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+const (
+	_schemaPostgresTables = `SELECT table_schema, table_name, table_type
+FROM information_schema.tables
+WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY table_schema, table_name`
+	_schemaPostgresColumns = `SELECT table_schema, table_name, column_name, data_type, is_nullable
+FROM information_schema.columns
+WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY table_schema, table_name, ordinal_position`
+	_schemaPostgresIndexes = `SELECT n.nspname, t.relname, i.relname, ix.indisunique,
+	array_to_string(array(
+		SELECT a.attname
+		FROM unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		ORDER BY k.ord
+	), ',')
+FROM pg_index ix
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_class i ON i.oid = ix.indexrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+ORDER BY n.nspname, t.relname, i.relname`
+	_schemaPostgresForeignKeys = `SELECT n.nspname, t.relname, c.conname, rn.nspname, rt.relname,
+	array_to_string(array(
+		SELECT a.attname
+		FROM unnest(c.conkey) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+		ORDER BY k.ord
+	), ','),
+	array_to_string(array(
+		SELECT a.attname
+		FROM unnest(c.confkey) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.attnum
+		ORDER BY k.ord
+	), ',')
+FROM pg_constraint c
+JOIN pg_class t ON t.oid = c.conrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN pg_class rt ON rt.oid = c.confrelid
+JOIN pg_namespace rn ON rn.oid = rt.relnamespace
+WHERE c.contype = 'f'
+ORDER BY n.nspname, t.relname, c.conname`
+)
+
+func introspectPostgres(ctx context.Context, db *sql.DB) (SQLSchemaResponse, error) {
+	b := newSchemaBuilder()
+
+	tables, err := queryStrings(ctx, db, _schemaPostgresTables)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get tables")
+	}
+	for _, row := range tables {
+		b.table(row[0], row[1]).IsView = row[2] == "VIEW"
+	}
+
+	columns, err := queryStrings(ctx, db, _schemaPostgresColumns)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get columns")
+	}
+	for _, row := range columns {
+		t := b.table(row[0], row[1])
+		t.Columns = append(t.Columns, sqlColumn{row[2], row[3], row[4] == "YES"})
+	}
+
+	indexes, err := queryStrings(ctx, db, _schemaPostgresIndexes)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get indexes")
+	}
+	for _, row := range indexes {
+		t := b.table(row[0], row[1])
+		t.Indexes = append(t.Indexes, sqlIndex{row[2], splitList(row[4]), isTrue(row[3])})
+	}
+
+	fks, err := queryStrings(ctx, db, _schemaPostgresForeignKeys)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get foreign keys")
+	}
+	for _, row := range fks {
+		t := b.table(row[0], row[1])
+		t.ForeignKeys = append(t.ForeignKeys, sqlForeignKey{row[2], splitList(row[5]), row[3], row[4], splitList(row[6])})
+	}
+
+	return b.build(), nil
+}
+
+const (
+	_mysqlSystemSchemas  = `('mysql', 'information_schema', 'performance_schema', 'sys')`
+	_schemaMysqlTables   = `SELECT table_schema, table_name, table_type FROM information_schema.tables WHERE table_schema NOT IN ` + _mysqlSystemSchemas + ` ORDER BY table_schema, table_name`
+	_schemaMysqlColumns  = `SELECT table_schema, table_name, column_name, column_type, is_nullable FROM information_schema.columns WHERE table_schema NOT IN ` + _mysqlSystemSchemas + ` ORDER BY table_schema, table_name, ordinal_position`
+	_schemaMysqlIndexes  = `SELECT table_schema, table_name, index_name, non_unique, column_name FROM information_schema.statistics WHERE table_schema NOT IN ` + _mysqlSystemSchemas + ` ORDER BY table_schema, table_name, index_name, seq_in_index`
+	_schemaMysqlForeigns = `SELECT table_schema, table_name, constraint_name, column_name, referenced_table_schema, referenced_table_name, referenced_column_name FROM information_schema.key_column_usage WHERE referenced_table_name IS NOT NULL AND table_schema NOT IN ` + _mysqlSystemSchemas + ` ORDER BY table_schema, table_name, constraint_name, ordinal_position`
+)
+
+func introspectMysql(ctx context.Context, db *sql.DB) (SQLSchemaResponse, error) {
+	b := newSchemaBuilder()
+
+	tables, err := queryStrings(ctx, db, _schemaMysqlTables)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get tables")
+	}
+	for _, row := range tables {
+		b.table(row[0], row[1]).IsView = row[2] == "VIEW"
+	}
+
+	columns, err := queryStrings(ctx, db, _schemaMysqlColumns)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get columns")
+	}
+	for _, row := range columns {
+		t := b.table(row[0], row[1])
+		t.Columns = append(t.Columns, sqlColumn{row[2], row[3], row[4] == "YES"})
+	}
+
+	// NOTE: statistics has one row per index column
+	indexes, err := queryStrings(ctx, db, _schemaMysqlIndexes)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get indexes")
+	}
+	for _, row := range indexes {
+		t := b.table(row[0], row[1])
+		if n := len(t.Indexes); n > 0 && t.Indexes[n-1].Name == row[2] {
+			t.Indexes[n-1].Columns = append(t.Indexes[n-1].Columns, row[4])
+		} else {
+			t.Indexes = append(t.Indexes, sqlIndex{row[2], []string{row[4]}, !isTrue(row[3])})
+		}
+	}
+
+	fks, err := queryStrings(ctx, db, _schemaMysqlForeigns)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get foreign keys")
+	}
+	for _, row := range fks {
+		t := b.table(row[0], row[1])
+		if n := len(t.ForeignKeys); n > 0 && t.ForeignKeys[n-1].Name == row[2] {
+			fk := &t.ForeignKeys[n-1]
+			fk.Columns = append(fk.Columns, row[3])
+			fk.RefColumns = append(fk.RefColumns, row[6])
+		} else {
+			t.ForeignKeys = append(t.ForeignKeys, sqlForeignKey{row[2], []string{row[3]}, row[4], row[5], []string{row[6]}})
+		}
+	}
+
+	return b.build(), nil
+}
+
+func introspectSqlite(ctx context.Context, db *sql.DB) (SQLSchemaResponse, error) {
+	const schema = "main"
+	b := newSchemaBuilder()
+
+	tables, err := queryStrings(ctx, db, `SELECT name, type FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get tables")
+	}
+	for _, row := range tables {
+		name := row[0]
+		t := b.table(schema, name)
+		t.IsView = row[1] == "view"
+
+		columns, err := queryStrings(ctx, db, `SELECT name, type, "notnull" FROM pragma_table_info(?) ORDER BY cid`, name)
+		if err != nil {
+			return SQLSchemaResponse{}, errors.Wrapf(err, "get columns of %q", name)
+		}
+		for _, col := range columns {
+			t.Columns = append(t.Columns, sqlColumn{col[0], col[1], !isTrue(col[2])})
+		}
+
+		indexes, err := queryStrings(ctx, db, `SELECT name, "unique" FROM pragma_index_list(?) ORDER BY seq`, name)
+		if err != nil {
+			return SQLSchemaResponse{}, errors.Wrapf(err, "get indexes of %q", name)
+		}
+		for _, idx := range indexes {
+			cols, err := queryStrings(ctx, db, `SELECT name FROM pragma_index_info(?) ORDER BY seqno`, idx[0])
+			if err != nil {
+				return SQLSchemaResponse{}, errors.Wrapf(err, "get columns of index %q", idx[0])
+			}
+
+			columns := make([]string, len(cols))
+			for i, col := range cols {
+				columns[i] = col[0]
+			}
+			t.Indexes = append(t.Indexes, sqlIndex{idx[0], columns, isTrue(idx[1])})
+		}
+
+		fks, err := queryStrings(ctx, db, `SELECT id, "table", "from", "to" FROM pragma_foreign_key_list(?) ORDER BY id, seq`, name)
+		if err != nil {
+			return SQLSchemaResponse{}, errors.Wrapf(err, "get foreign keys of %q", name)
+		}
+		for _, fk := range fks {
+			if n := len(t.ForeignKeys); n > 0 && t.ForeignKeys[n-1].Name == fk[0] {
+				last := &t.ForeignKeys[n-1]
+				last.Columns = append(last.Columns, fk[2])
+				last.RefColumns = append(last.RefColumns, fk[3])
+			} else {
+				t.ForeignKeys = append(t.ForeignKeys, sqlForeignKey{fk[0], []string{fk[2]}, schema, fk[1], []string{fk[3]}})
+			}
+		}
+	}
+
+	return b.build(), nil
+}
+
+const (
+	_clickhouseSystemDatabases = `('system', 'INFORMATION_SCHEMA', 'information_schema')`
+	_schemaClickhouseTables    = `SELECT database, name, engine, primary_key FROM system.tables WHERE database NOT IN ` + _clickhouseSystemDatabases + ` ORDER BY database, name`
+	_schemaClickhouseColumns   = `SELECT database, table, name, type FROM system.columns WHERE database NOT IN ` + _clickhouseSystemDatabases + ` ORDER BY database, table, position`
+	_schemaClickhouseIndexes   = `SELECT database, table, name, expr FROM system.data_skipping_indices WHERE database NOT IN ` + _clickhouseSystemDatabases + ` ORDER BY database, table, name`
+)
+
+func introspectClickhouse(ctx context.Context, db *sql.DB) (SQLSchemaResponse, error) {
+	b := newSchemaBuilder()
+
+	tables, err := queryStrings(ctx, db, _schemaClickhouseTables)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get tables")
+	}
+	for _, row := range tables {
+		t := b.table(row[0], row[1])
+		t.IsView = row[2] == "View" || row[2] == "MaterializedView"
+		if row[3] != "" {
+			t.Indexes = append(t.Indexes, sqlIndex{"PRIMARY KEY", strings.Split(row[3], ", "), false})
+		}
+	}
+
+	columns, err := queryStrings(ctx, db, _schemaClickhouseColumns)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get columns")
+	}
+	for _, row := range columns {
+		t := b.table(row[0], row[1])
+		t.Columns = append(t.Columns, sqlColumn{row[2], row[3], strings.HasPrefix(row[3], "Nullable(")})
+	}
+
+	indexes, err := queryStrings(ctx, db, _schemaClickhouseIndexes)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "get indexes")
+	}
+	for _, row := range indexes {
+		t := b.table(row[0], row[1])
+		t.Indexes = append(t.Indexes, sqlIndex{row[2], []string{row[3]}, false})
+	}
+
+	// NOTE: clickhouse has no foreign keys
+	return b.build(), nil
+}
+
+func (a *App) introspectSQL(req database.SQLRequest) (SQLSchemaResponse, error) {
+	db, err := a.openSQL(req)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "connect to database")
+	}
+	defer db.Close()
+
+	switch req.Database {
+	case database.Postgres:
+		return introspectPostgres(a.ctx, db)
+	case database.MySQL:
+		return introspectMysql(a.ctx, db)
+	case database.SQLite:
+		return introspectSqlite(a.ctx, db)
+	case database.Clickhouse:
+		return introspectClickhouse(a.ctx, db)
+	default:
+		return SQLSchemaResponse{}, errors.Errorf("unsupported database: %s", req.Database)
+	}
+}
+
+func (a *App) getSQLRequest(id string) (database.SQLRequest, error) {
+	request, err := database.Get(a.ctx, a.DB, database.RequestID(id))
+	if err != nil {
+		return database.SQLRequest{}, errors.Wrapf(err, "get request id=%q", id)
+	}
+
+	req, ok := request.Data.(database.SQLRequest)
+	if !ok {
+		return database.SQLRequest{}, errors.Errorf("query kind is %s, expected sql", request.Data.Kind())
+	}
+	return req, nil
+}
+
+func sqlSchemaKey(req database.SQLRequest) string {
+	key := string(req.Database) + " " + req.DSN
+	if req.SSH != nil {
+		key += " ssh " + req.SSH.Host
+	}
+	return key
+}
+
+// SQLSchema returns schema of database used by request, schema is cached per DSN
+func (a *App) SQLSchema(requestID string) (SQLSchemaResponse, error) {
+	req, err := a.getSQLRequest(requestID)
+	if err != nil {
+		return SQLSchemaResponse{}, err
+	}
+
+	key := sqlSchemaKey(req)
+	a.sqlSchemasMu.Lock()
+	schema, ok := a.sqlSchemas[key]
+	a.sqlSchemasMu.Unlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err = a.introspectSQL(req)
+	if err != nil {
+		return SQLSchemaResponse{}, errors.Wrap(err, "introspect database")
+	}
+
+	a.sqlSchemasMu.Lock()
+	a.sqlSchemas[key] = schema
+	a.sqlSchemasMu.Unlock()
+	return schema, nil
+}
+
+// SQLSchemaRefresh drops cached schema of request database and introspects it again
+func (a *App) SQLSchemaRefresh(requestID string) (SQLSchemaResponse, error) {
+	req, err := a.getSQLRequest(requestID)
+	if err != nil {
+		return SQLSchemaResponse{}, err
+	}
+
+	a.sqlSchemasMu.Lock()
+	delete(a.sqlSchemas, sqlSchemaKey(req))
+	a.sqlSchemasMu.Unlock()
+
+	return a.SQLSchema(requestID)
+}
@@ -0,0 +1,245 @@
+package app
+
+import (
+	"strings"
+	"unicode"
+)
+
+// splitStatements splits sql script into statements separated by semicolons.
+// Semicolons inside quotes, comments and postgres dollar-quoted strings are ignored.
+func splitStatements(script string) []string {
+	var res []string
+	start := 0
+	flush := func(end int) {
+		if stmt := strings.TrimSpace(script[start:end]); stmt != "" && !isOnlyComments(stmt) {
+			res = append(res, stmt)
+		}
+		start = end + 1
+	}
+
+	for i := 0; i < len(script); i++ {
+		if j, ok := skipNonCode(script, i); ok {
+			i = j
+		} else if script[i] == ';' {
+			flush(i)
+		}
+	}
+	if start < len(script) {
+		flush(len(script))
+	}
+	return res
+}
+
+// skipNonCode returns index of last byte of quoted string, comment or dollar-quoted string starting at i.
+// Returns false if code continues at i.
+func skipNonCode(s string, i int) (int, bool) {
+	switch c := s[i]; {
+	case c == '\'' || c == '"' || c == '`':
+		return skipQuoted(s, i, c), true
+	case c == '-' && strings.HasPrefix(s[i:], "--"):
+		if j := strings.IndexByte(s[i:], '\n'); j != -1 {
+			return i + j, true
+		}
+		return len(s), true
+	case c == '/' && strings.HasPrefix(s[i:], "/*"):
+		if j := strings.Index(s[i+2:], "*/"); j != -1 {
+			return i + 2 + j + 1, true
+		}
+		return len(s), true
+	case c == '$':
+		tag, ok := dollarTag(s[i:])
+		if !ok {
+			return 0, false
+		}
+		if j := strings.Index(s[i+len(tag):], tag); j != -1 {
+			return i + len(tag) + j + len(tag) - 1, true
+		}
+		return len(s), true
+	default:
+		return 0, false
+	}
+}
+
+// skipQuoted returns index of closing quote for quote opened at i, doubled quotes are skipped
+func skipQuoted(s string, i int, quote byte) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != quote {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == quote {
+			j++
+			continue
+		}
+		return j
+	}
+	return len(s)
+}
+
+// dollarTag parses postgres dollar quote tag like $$ or $body$ at the start of s
+func dollarTag(s string) (string, bool) {
+	for j := 1; j < len(s); j++ {
+		c := rune(s[j])
+		switch {
+		case c == '$':
+			return s[:j+1], true
+		case c == '_' || unicode.IsLetter(c) || j > 1 && unicode.IsDigit(c):
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func stripComments(stmt string) string {
+	for {
+		stmt = strings.TrimSpace(stmt)
+		switch {
+		case strings.HasPrefix(stmt, "--"):
+			j := strings.IndexByte(stmt, '\n')
+			if j == -1 {
+				return ""
+			}
+			stmt = stmt[j+1:]
+		case strings.HasPrefix(stmt, "/*"):
+			j := strings.Index(stmt, "*/")
+			if j == -1 {
+				return ""
+			}
+			stmt = stmt[j+2:]
+		default:
+			return stmt
+		}
+	}
+}
+
+func isOnlyComments(stmt string) bool {
+	return stripComments(stmt) == ""
+}
+
+// returnsRows guesses whether statement produces result set
+func returnsRows(stmt string) bool {
+	stmt = strings.TrimLeft(stripComments(stmt), "( \t\r\n")
+	keyword, _, _ := strings.Cut(stmt, " ")
+	keyword = strings.ToLower(strings.TrimRightFunc(keyword, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}))
+	if keyword == "with" {
+		// NOTE: CTEs may be followed by data modifying statement
+		keyword, stmt = withMainStatement(stmt)
+	}
+	switch keyword {
+	case "select", "show", "explain", "describe", "desc", "values", "table", "pragma", "exists":
+		return true
+	default:
+		return hasKeyword(stmt, "returning")
+	}
+}
+
+// withMainStatement returns lowercased keyword and text of statement following CTEs of WITH statement
+func withMainStatement(stmt string) (string, string) {
+	depth := 0
+	for i := 0; i < len(stmt); i++ {
+		if j, ok := skipNonCode(stmt, i); ok {
+			i = j
+			continue
+		}
+
+		switch c := stmt[i]; {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && isSQLWord(c) && (i == 0 || !isSQLWord(stmt[i-1])):
+			end := i
+			for end < len(stmt) && isSQLWord(stmt[end]) {
+				end++
+			}
+			switch word := strings.ToLower(stmt[i:end]); word {
+			case "select", "insert", "update", "delete", "merge", "values", "table":
+				return word, stmt[i:]
+			}
+			i = end - 1
+		}
+	}
+	return "", stmt
+}
+
+// isReadOnly guesses whether statement does not change data, session settings like SET and USE are allowed
+func isReadOnly(stmt string) bool {
+	stmt = strings.TrimLeft(stripComments(stmt), "( \t\r\n")
+	keyword, _, _ := strings.Cut(stmt, " ")
+	keyword = strings.ToLower(strings.TrimRightFunc(keyword, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}))
+	switch keyword {
+	case "select", "with":
+		// NOTE: SELECT INTO creates table, CTE may wrap data modifying statements
+		for _, keyword := range []string{"into", "insert", "update", "delete", "merge"} {
+			if hasKeyword(stmt, keyword) {
+				return false
+			}
+		}
+		return true
+	case "explain":
+		return !hasKeyword(stmt, "analyze")
+	case "show", "describe", "desc", "values", "table", "exists", "set", "use":
+		return true
+	default:
+		return false
+	}
+}
+
+func isSQLWord(b byte) bool {
+	return b == '_' || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
+
+// hasKeyword reports whether stmt contains keyword as separate word outside of quotes and comments
+func hasKeyword(stmt, keyword string) bool {
+	lower, keyword := strings.ToLower(stmt), strings.ToLower(keyword)
+	for i := 0; i < len(lower); i++ {
+		if j, ok := skipNonCode(lower, i); ok {
+			i = j
+			continue
+		}
+
+		end := i + len(keyword)
+		if strings.HasPrefix(lower[i:], keyword) &&
+			(i == 0 || !isSQLWord(lower[i-1])) && (end == len(lower) || !isSQLWord(lower[end])) {
+			return true
+		}
+	}
+	return false
+}
+
+// countPlaceholders returns highest postgres $N placeholder and number of ? placeholders in statement
+func countPlaceholders(stmt string) (dollarMax, questions int) {
+	for i := 0; i < len(stmt); i++ {
+		if j, ok := skipNonCode(stmt, i); ok {
+			i = j
+			continue
+		}
+
+		switch stmt[i] {
+		case '?':
+			questions++
+		case '$':
+			n := 0
+			for i+1 < len(stmt) && '0' <= stmt[i+1] && stmt[i+1] <= '9' {
+				n = n*10 + int(stmt[i+1]-'0')
+				i++
+			}
+			dollarMax = max(dollarMax, n)
+		}
+	}
+	return dollarMax, questions
+}
+
+// hasNamedPlaceholder reports whether statement references sqlite named parameter
+func hasNamedPlaceholder(stmt, name string) bool {
+	for _, prefix := range []string{":", "@", "$"} {
+		if hasKeyword(stmt, prefix+name) {
+			return true
+		}
+	}
+	return false
+}
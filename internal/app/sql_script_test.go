@@ -0,0 +1,134 @@
+package app
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"single without semicolon", "SELECT 1", []string{"SELECT 1"}},
+		{"several", "SELECT 1; SELECT 2;\nSELECT 3;", []string{"SELECT 1", "SELECT 2", "SELECT 3"}},
+		{"empty statements", ";; SELECT 1 ;;", []string{"SELECT 1"}},
+		{"semicolon in single quotes", "SELECT 'a;b'; SELECT 2", []string{"SELECT 'a;b'", "SELECT 2"}},
+		{"doubled quote", "SELECT 'it''s;'; SELECT 2", []string{"SELECT 'it''s;'", "SELECT 2"}},
+		{"semicolon in double quotes", `SELECT 1 AS "a;b"; SELECT 2`, []string{`SELECT 1 AS "a;b"`, "SELECT 2"}},
+		{"semicolon in backticks", "SELECT 1 AS `a;b`; SELECT 2", []string{"SELECT 1 AS `a;b`", "SELECT 2"}},
+		{"line comment", "SELECT 1; -- a; b\nSELECT 2", []string{"SELECT 1", "-- a; b\nSELECT 2"}},
+		{"block comment", "SELECT /* a; b */ 1; SELECT 2", []string{"SELECT /* a; b */ 1", "SELECT 2"}},
+		{"only comments", "SELECT 1; -- done\n/* really */", []string{"SELECT 1"}},
+		{"unterminated comment", "SELECT 1; /* a; b", []string{"SELECT 1"}},
+		{"dollar quoted", "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql; SELECT f()", []string{
+			"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql",
+			"SELECT f()",
+		}},
+		{"dollar tag", "DO $body$ BEGIN PERFORM 1; END $body$; SELECT 2", []string{"DO $body$ BEGIN PERFORM 1; END $body$", "SELECT 2"}},
+		{"nested dollar tags", "DO $a$ SELECT $b$;$b$; $a$; SELECT 2", []string{"DO $a$ SELECT $b$;$b$; $a$", "SELECT 2"}},
+		{"placeholder is not tag", "SELECT $1; SELECT $2", []string{"SELECT $1", "SELECT $2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := splitStatements(tc.script); !slices.Equal(got, tc.want) {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReturnsRows(t *testing.T) {
+	for _, tc := range []struct {
+		stmt string
+		want bool
+	}{
+		{"SELECT 1", true},
+		{"select 1", true},
+		{"(SELECT 1) UNION (SELECT 2)", true},
+		{"-- comment\n/* block */ SELECT 1", true},
+		{"SHOW TABLES", true},
+		{"EXPLAIN SELECT 1", true},
+		{"VALUES (1), (2)", true},
+		{"PRAGMA table_info(t)", true},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{"WITH RECURSIVE x(n) AS (SELECT 1 UNION ALL SELECT n+1 FROM x) SELECT n FROM x", true},
+		{"WITH x AS (SELECT 1) INSERT INTO t SELECT * FROM x", false},
+		{"WITH x AS (SELECT 1) INSERT INTO t SELECT * FROM x RETURNING id", true},
+		{"WITH d AS (DELETE FROM t RETURNING id) INSERT INTO log SELECT id FROM d", false},
+		{"WITH d AS (DELETE FROM t RETURNING id) SELECT * FROM d", true},
+		{"INSERT INTO t VALUES (1)", false},
+		{"INSERT INTO t VALUES (1) RETURNING id", true},
+		{"INSERT INTO t VALUES ('returning')", false},
+		{"INSERT INTO t VALUES (1) -- returning", false},
+		{"UPDATE t SET returning_count = 1", false},
+		{"DELETE FROM t", false},
+		{"CREATE TABLE t (id int)", false},
+		{"CREATE FUNCTION f() RETURNS SETOF t AS $$ DELETE FROM t RETURNING * $$ LANGUAGE sql", false},
+	} {
+		t.Run(tc.stmt, func(t *testing.T) {
+			if got := returnsRows(tc.stmt); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	for _, tc := range []struct {
+		stmt string
+		want bool
+	}{
+		{"SELECT 1", true},
+		{"/* comment */ SELECT * FROM t", true},
+		{"SELECT 'insert into' FROM t", true},
+		{"SELECT 1 -- delete everything", true},
+		{"SELECT * INTO backup FROM t", false},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{"WITH x AS (SELECT 1) INSERT INTO t SELECT * FROM x", false},
+		{"WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d", false},
+		{"EXPLAIN SELECT 1", true},
+		{"EXPLAIN ANALYZE DELETE FROM t", false},
+		{"SHOW TABLES", true},
+		{"SET search_path TO public", true},
+		{"USE db", true},
+		{"INSERT INTO t VALUES (1)", false},
+		{"TRUNCATE t", false},
+	} {
+		t.Run(tc.stmt, func(t *testing.T) {
+			if got := isReadOnly(tc.stmt); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHasKeyword(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		stmt    string
+		keyword string
+		want    bool
+	}{
+		{"word", "INSERT INTO t VALUES (1) RETURNING id", "returning", true},
+		{"case insensitive", "insert into t values (1) Returning id", "RETURNING", true},
+		{"at end", "DELETE FROM t RETURNING", "returning", true},
+		{"prefix of word", "SELECT returning_id FROM t", "returning", false},
+		{"suffix of word", "SELECT not_returning FROM t", "returning", false},
+		{"single quotes", "SELECT 'returning'", "returning", false},
+		{"double quotes", `SELECT 1 AS "returning"`, "returning", false},
+		{"backticks", "SELECT 1 AS `returning`", "returning", false},
+		{"line comment", "SELECT 1 -- returning\n", "returning", false},
+		{"block comment", "SELECT /* returning */ 1", "returning", false},
+		{"dollar quoted", "SELECT $$returning$$", "returning", false},
+		{"named placeholder", "SELECT * FROM t WHERE id = :id", ":id", true},
+		{"named placeholder prefix", "SELECT * FROM t WHERE id = :id2", ":id", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := hasKeyword(tc.stmt, tc.keyword); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// sqlSession is transaction opened on dedicated connection for request.
+// While session is open, all request runs are performed inside the transaction.
+type sqlSession struct {
+	mu        sync.Mutex
+	database  database.Database
+	dsn       string
+	startedAt time.Time
+	db        *sql.DB
+	conn      *sql.Conn
+	tx        *sql.Tx
+}
+
+func (s *sqlSession) run(ctx context.Context, req database.SQLRequest) (database.SQLResponse, error) {
+	if req.Database != s.database || req.DSN != s.dsn {
+		return database.SQLResponse{}, errors.New("transaction is open on another database, commit or rollback it first")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return runSQLScript(ctx, s.tx, req)
+}
+
+func (s *sqlSession) close() error {
+	connErr := s.conn.Close()
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	return connErr
+}
+
+type sqlTransaction struct {
+	RequestID string            `json:"request_id"`
+	Database  database.Database `json:"database"`
+	StartedAt time.Time         `json:"started_at"`
+}
+
+func (a *App) getSQLSession(id database.RequestID) (*sqlSession, bool) {
+	a.sqlSessionsMu.Lock()
+	defer a.sqlSessionsMu.Unlock()
+
+	session, ok := a.sqlSessions[id]
+	return session, ok
+}
+
+func (a *App) popSQLSession(id database.RequestID) (*sqlSession, error) {
+	a.sqlSessionsMu.Lock()
+	defer a.sqlSessionsMu.Unlock()
+
+	session, ok := a.sqlSessions[id]
+	if !ok {
+		return nil, errors.Errorf("no open transaction for request %q", id)
+	}
+	delete(a.sqlSessions, id)
+	return session, nil
+}
+
+// SQLBegin opens dedicated connection and begins transaction for request
+func (a *App) SQLBegin(requestID string) error {
+	req, err := a.getSQLRequest(requestID)
+	if err != nil {
+		return err
+	}
+	if req.Database == database.Clickhouse {
+		return errors.New("clickhouse does not support transactions")
+	}
+
+	id := database.RequestID(requestID)
+	if _, ok := a.getSQLSession(id); ok {
+		return errors.Errorf("transaction for request %q is already open", requestID)
+	}
+
+	db, err := a.openSQL(req)
+	if err != nil {
+		return errors.Wrap(err, "connect to database")
+	}
+
+	conn, err := db.Conn(a.ctx)
+	if err != nil {
+		db.Close()
+		return errors.Wrap(err, "get connection")
+	}
+
+	tx, err := conn.BeginTx(a.ctx, nil)
+	if err != nil {
+		conn.Close()
+		db.Close()
+		return errors.Wrap(err, "begin transaction")
+	}
+
+	a.sqlSessionsMu.Lock()
+	defer a.sqlSessionsMu.Unlock()
+	if _, ok := a.sqlSessions[id]; ok {
+		// NOTE: other call has begun transaction meanwhile
+		tx.Rollback()
+		conn.Close()
+		db.Close()
+		return errors.Errorf("transaction for request %q is already open", requestID)
+	}
+	a.sqlSessions[id] = &sqlSession{
+		database:  req.Database,
+		dsn:       req.DSN,
+		startedAt: time.Now(),
+		db:        db,
+		conn:      conn,
+		tx:        tx,
+	}
+	return nil
+}
+
+// SQLCommit commits transaction of request and releases its connection
+func (a *App) SQLCommit(requestID string) error {
+	session, err := a.popSQLSession(database.RequestID(requestID))
+	if err != nil {
+		return err
+	}
+	defer session.close()
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if err := session.tx.Commit(); err != nil {
+		return errors.Wrap(err, "commit")
+	}
+	return nil
+}
+
+// SQLRollback rolls back transaction of request and releases its connection
+func (a *App) SQLRollback(requestID string) error {
+	session, err := a.popSQLSession(database.RequestID(requestID))
+	if err != nil {
+		return err
+	}
+	defer session.close()
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if err := session.tx.Rollback(); err != nil {
+		return errors.Wrap(err, "rollback")
+	}
+	return nil
+}
+
+// SQLTransactions lists open transactions
+func (a *App) SQLTransactions() []sqlTransaction {
+	a.sqlSessionsMu.Lock()
+	defer a.sqlSessionsMu.Unlock()
+
+	res := make([]sqlTransaction, 0, len(a.sqlSessions))
+	for id, session := range a.sqlSessions {
+		res = append(res, sqlTransaction{string(id), session.database, session.startedAt})
+	}
+	slices.SortFunc(res, func(a, b sqlTransaction) int {
+		return strings.Compare(a.RequestID, b.RequestID)
+	})
+	return res
+}
+
+// rollbackSQLSessions rolls back all open transactions, used on app close
+func (a *App) rollbackSQLSessions() {
+	a.sqlSessionsMu.Lock()
+	sessions := a.sqlSessions
+	a.sqlSessions = map[database.RequestID]*sqlSession{}
+	a.sqlSessionsMu.Unlock()
+
+	for id, session := range sessions {
+		session.mu.Lock() // NOTE: wait for running statements
+		if err := session.tx.Rollback(); err != nil {
+			log.Error().Err(err).Str("request_id", string(id)).Msg("rollback transaction")
+		}
+		if err := session.close(); err != nil {
+			log.Error().Err(err).Str("request_id", string(id)).Msg("close connection")
+		}
+		session.mu.Unlock()
+	}
+}
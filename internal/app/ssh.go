@@ -0,0 +1,264 @@
+package app
+
+import (
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// _sshTimeout limits ssh handshake and keepalive check, so that unreachable server does not hang requests
+const _sshTimeout = 10 * time.Second
+
+// sshTunnel forwards connections accepted on local listener to remote address through ssh client
+type sshTunnel struct {
+	client   *ssh.Client
+	listener net.Listener
+	remote   string
+}
+
+func (t *sshTunnel) alive() bool {
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := t.client.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err == nil
+	case <-time.After(_sshTimeout):
+		return false
+	}
+}
+
+func (t *sshTunnel) close() error {
+	listenerErr := t.listener.Close()
+	if err := t.client.Close(); err != nil {
+		return err
+	}
+	return listenerErr
+}
+
+func (t *sshTunnel) serve() {
+	for {
+		local, err := t.listener.Accept()
+		if err != nil {
+			return // NOTE: listener is closed
+		}
+
+		go func() {
+			defer local.Close()
+
+			remote, err := t.client.Dial("tcp", t.remote)
+			if err != nil {
+				log.Error().Err(err).Str("remote", t.remote).Msg("dial through ssh tunnel")
+				return
+			}
+			defer remote.Close()
+
+			done := make(chan struct{}, 2)
+			go func() {
+				_, _ = io.Copy(remote, local)
+				done <- struct{}{}
+			}()
+			go func() {
+				_, _ = io.Copy(local, remote)
+				done <- struct{}{}
+			}()
+			<-done
+		}()
+	}
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+func sshHostKeyCallback(policy database.KnownHostsPolicy) (ssh.HostKeyCallback, error) {
+	if policy == database.KnownHostsInsecure {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	path := expandHome("~/.ssh/known_hosts")
+	switch policy {
+	case database.KnownHostsStrict:
+		return knownhosts.New(path)
+	case database.KnownHostsAcceptNew:
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, errors.Wrap(err, "create ssh dir")
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+		if err != nil {
+			return nil, errors.Wrap(err, "create known hosts file")
+		}
+		f.Close()
+
+		check, err := knownhosts.New(path)
+		if err != nil {
+			return nil, err
+		}
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			err := check(hostname, remote, key)
+			var keyErr *knownhosts.KeyError
+			if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+				return err // NOTE: known host or changed key
+			}
+
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+			if err != nil {
+				return errors.Wrap(err, "open known hosts file")
+			}
+			defer f.Close()
+
+			line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+			if _, err := f.WriteString(line + "\n"); err != nil {
+				return errors.Wrap(err, "add host to known hosts")
+			}
+			return nil
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown known hosts policy %q", policy)
+	}
+}
+
+func dialSSH(cfg database.SSHTunnel) (*ssh.Client, error) {
+	addr := cfg.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	username := cfg.User
+	if username == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, errors.Wrap(err, "get current user")
+		}
+		username = u.Username
+	}
+
+	hostKeyCallback, err := sshHostKeyCallback(cfg.KnownHosts)
+	if err != nil {
+		return nil, errors.Wrap(err, "load known hosts")
+	}
+
+	var auth ssh.AuthMethod
+	if cfg.KeyFile != "" {
+		key, err := os.ReadFile(expandHome(cfg.KeyFile))
+		if err != nil {
+			return nil, errors.Wrap(err, "read key file")
+		}
+
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			var passErr *ssh.PassphraseMissingError
+			if errors.As(err, &passErr) {
+				return nil, errors.New("key file is encrypted, add it to ssh agent and leave key file empty")
+			}
+			return nil, errors.Wrap(err, "parse key file")
+		}
+		auth = ssh.PublicKeys(signer)
+	} else {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, errors.New("no key file given and SSH_AUTH_SOCK is not set")
+		}
+
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, errors.Wrap(err, "connect to ssh agent")
+		}
+		defer conn.Close()
+		auth = ssh.PublicKeysCallback(agent.NewClient(conn).Signers)
+	}
+
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            username,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         _sshTimeout,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "ssh dial %s", addr)
+	}
+	return client, nil
+}
+
+// tunnel returns local address forwarding to remote through ssh server.
+// If cfg is nil, remote is returned as is. Tunnels are reused while ssh connection is alive.
+func (a *App) tunnel(cfg *database.SSHTunnel, remote string) (string, error) {
+	if cfg == nil {
+		return remote, nil
+	}
+
+	key := strings.Join([]string{cfg.Host, cfg.User, cfg.KeyFile, string(cfg.KnownHosts), remote}, " ")
+
+	// NOTE: concurrent calls for the same tunnel wait for single dial, other tunnels are not blocked
+	addr, err, _ := a.sshDials.Do(key, func() (any, error) {
+		a.sshTunnelsMu.Lock()
+		t, ok := a.sshTunnels[key]
+		a.sshTunnelsMu.Unlock()
+		if ok {
+			if t.alive() {
+				return t.listener.Addr().String(), nil
+			}
+
+			a.sshTunnelsMu.Lock()
+			delete(a.sshTunnels, key)
+			a.sshTunnelsMu.Unlock()
+			_ = t.close()
+		}
+
+		client, err := dialSSH(*cfg)
+		if err != nil {
+			return "", err
+		}
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			client.Close()
+			return "", errors.Wrap(err, "listen local port")
+		}
+
+		t = &sshTunnel{client, listener, remote}
+		go t.serve()
+
+		a.sshTunnelsMu.Lock()
+		a.sshTunnels[key] = t
+		a.sshTunnelsMu.Unlock()
+		return listener.Addr().String(), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return addr.(string), nil
+}
+
+// closeSSHTunnels closes all tunnels, used on app close
+func (a *App) closeSSHTunnels() {
+	a.sshTunnelsMu.Lock()
+	defer a.sshTunnelsMu.Unlock()
+
+	for key, t := range a.sshTunnels {
+		if err := t.close(); err != nil {
+			log.Error().Err(err).Str("remote", t.remote).Msg("close ssh tunnel")
+		}
+		delete(a.sshTunnels, key)
+	}
+}
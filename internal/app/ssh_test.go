@@ -0,0 +1,394 @@
+package app
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/rprtr258/impulse/internal/database"
+)
+
+// testSSHServer accepts clients authorized by single key and serves direct-tcpip channels
+type testSSHServer struct {
+	addr    string
+	hostKey ssh.Signer
+
+	mu       sync.Mutex
+	accepted int
+	conns    []*ssh.ServerConn
+}
+
+func newTestKey(t *testing.T) (ssh.Signer, ed25519.PrivateKey) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, key
+}
+
+// writeTestKey writes private key in openssh format and returns path to it
+func writeTestKey(t *testing.T, key ed25519.PrivateKey) string {
+	t.Helper()
+
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func startTestSSHServer(t *testing.T, authorized ssh.PublicKey) *testSSHServer {
+	t.Helper()
+
+	hostKey, _ := newTestKey(t)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(authorized.Marshal()) {
+				return nil, os.ErrPermission
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &testSSHServer{addr: listener.Addr().String(), hostKey: hostKey}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.mu.Lock()
+	s.accepted++
+	s.conns = append(s.conns, serverConn)
+	s.mu.Unlock()
+
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "only direct-tcpip is supported")
+			continue
+		}
+
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.FormatUint(uint64(target.Port), 10)))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			remote.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channelRequests)
+		go func() {
+			defer channel.Close()
+			defer remote.Close()
+			go io.Copy(remote, channel)
+			io.Copy(channel, remote)
+		}()
+	}
+}
+
+func (s *testSSHServer) acceptedConns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+// drop closes all accepted connections, as if server has gone away
+func (s *testSSHServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+// startEchoServer returns address of server writing back everything it reads
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// writeKnownHosts writes known_hosts with key of host into fresh home directory, file is empty if key is nil
+func writeKnownHosts(t *testing.T, host string, key ssh.PublicKey) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	var content string
+	if key != nil {
+		content = knownhosts.Line([]string{knownhosts.Normalize(host)}, key) + "\n"
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+func TestDialSSHKeyAuth(t *testing.T) {
+	signer, key := newTestKey(t)
+	_, otherKey := newTestKey(t)
+	server := startTestSSHServer(t, signer.PublicKey())
+
+	for _, tc := range []struct {
+		name    string
+		keyFile string
+		wantErr bool
+	}{
+		{"authorized key", writeTestKey(t, key), false},
+		{"unknown key", writeTestKey(t, otherKey), true},
+		{"missing key file", filepath.Join(t.TempDir(), "missing"), true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := dialSSH(database.SSHTunnel{
+				Host:       server.addr,
+				User:       "test",
+				KeyFile:    tc.keyFile,
+				KnownHosts: database.KnownHostsInsecure,
+			})
+			if tc.wantErr {
+				if err == nil {
+					client.Close()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.Close()
+		})
+	}
+}
+
+func TestDialSSHKnownHosts(t *testing.T) {
+	signer, key := newTestKey(t)
+	server := startTestSSHServer(t, signer.PublicKey())
+	keyFile := writeTestKey(t, key)
+	otherHostKey, _ := newTestKey(t)
+
+	for _, tc := range []struct {
+		name    string
+		known   ssh.PublicKey // NOTE: key of server in known_hosts, nil for empty file
+		policy  database.KnownHostsPolicy
+		wantErr bool
+	}{
+		{"strict known", server.hostKey.PublicKey(), database.KnownHostsStrict, false},
+		{"strict unknown", nil, database.KnownHostsStrict, true},
+		{"strict changed", otherHostKey.PublicKey(), database.KnownHostsStrict, true},
+		{"accept new unknown", nil, database.KnownHostsAcceptNew, false},
+		{"accept new changed", otherHostKey.PublicKey(), database.KnownHostsAcceptNew, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			writeKnownHosts(t, server.addr, tc.known)
+
+			client, err := dialSSH(database.SSHTunnel{
+				Host:       server.addr,
+				User:       "test",
+				KeyFile:    keyFile,
+				KnownHosts: tc.policy,
+			})
+			if tc.wantErr {
+				if err == nil {
+					client.Close()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.Close()
+		})
+	}
+}
+
+func TestDialSSHAcceptNewAddsHost(t *testing.T) {
+	signer, key := newTestKey(t)
+	server := startTestSSHServer(t, signer.PublicKey())
+	cfg := database.SSHTunnel{
+		Host:       server.addr,
+		User:       "test",
+		KeyFile:    writeTestKey(t, key),
+		KnownHosts: database.KnownHostsAcceptNew,
+	}
+	home := writeKnownHosts(t, server.addr, nil)
+
+	client, err := dialSSH(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	b, err := os.ReadFile(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := knownhosts.Line([]string{knownhosts.Normalize(server.addr)}, server.hostKey.PublicKey()); strings.TrimSpace(string(b)) != want {
+		t.Fatalf("known_hosts is %q, want %q", b, want)
+	}
+
+	cfg.KnownHosts = database.KnownHostsStrict
+	client, err = dialSSH(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+}
+
+// echoThrough sends message through local address of tunnel and checks it comes back
+func echoThrough(t *testing.T, addr, msg string) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != msg {
+		t.Fatalf("got %q, want %q", buf, msg)
+	}
+}
+
+func TestTunnelReuse(t *testing.T) {
+	signer, key := newTestKey(t)
+	server := startTestSSHServer(t, signer.PublicKey())
+	echo := startEchoServer(t)
+	cfg := &database.SSHTunnel{
+		Host:       server.addr,
+		User:       "test",
+		KeyFile:    writeTestKey(t, key),
+		KnownHosts: database.KnownHostsInsecure,
+	}
+
+	a, start, stop := New(afero.NewMemMapFs())
+	start(context.Background())
+	defer stop()
+
+	addr, err := a.tunnel(cfg, echo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoThrough(t, addr, "first")
+
+	var wg sync.WaitGroup
+	addrs := make([]string, 5)
+	errs := make([]error, len(addrs))
+	for i := range addrs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addrs[i], errs[i] = a.tunnel(cfg, echo)
+		}()
+	}
+	wg.Wait()
+	for i := range addrs {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if addrs[i] != addr {
+			t.Fatalf("tunnel is not reused: got %s, want %s", addrs[i], addr)
+		}
+	}
+	if n := server.acceptedConns(); n != 1 {
+		t.Fatalf("server accepted %d connections, want 1", n)
+	}
+
+	server.drop()
+
+	addr, err = a.tunnel(cfg, echo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoThrough(t, addr, "second")
+	if n := server.acceptedConns(); n != 2 {
+		t.Fatalf("server accepted %d connections, want 2", n)
+	}
+}
@@ -24,6 +24,50 @@ var decoderKVs = json2.Map(func(m fun.Option[[]KV]) []KV {
 	json2.Field("value", json2.String),
 ))))
 
+type KnownHostsPolicy string
+
+const (
+	// KnownHostsStrict requires host key to be present in ~/.ssh/known_hosts
+	KnownHostsStrict KnownHostsPolicy = "strict"
+	// KnownHostsAcceptNew adds unknown host keys to ~/.ssh/known_hosts, but rejects changed ones
+	KnownHostsAcceptNew KnownHostsPolicy = "accept_new"
+	// KnownHostsInsecure does not check host key at all
+	KnownHostsInsecure KnownHostsPolicy = "insecure"
+)
+
+var AllKnownHostsPolicies = []enumElem[KnownHostsPolicy]{
+	{KnownHostsStrict, "STRICT"},
+	{KnownHostsAcceptNew, "ACCEPT_NEW"},
+	{KnownHostsInsecure, "INSECURE"},
+}
+
+// SSHTunnel describes ssh server through which target is dialed
+type SSHTunnel struct {
+	// Host is ssh server address, port 22 is used if not specified
+	Host string `json:"host"`
+	User string `json:"user"`
+	// KeyFile is path to private key, ssh agent is used if empty
+	KeyFile    string           `json:"key_file"`
+	KnownHosts KnownHostsPolicy `json:"known_hosts"`
+}
+
+var decoderSSHTunnel = json2.Map(func(m fun.Option[SSHTunnel]) *SSHTunnel {
+	if !m.Valid {
+		return nil
+	}
+	return &m.Value
+}, json2.Nullable(json2.Map4(
+	func(host, user, keyFile string, knownHosts KnownHostsPolicy) SSHTunnel {
+		return SSHTunnel{host, user, keyFile, knownHosts}
+	},
+	json2.Required("host", json2.String),
+	json2.Optional("user", json2.String, ""),
+	json2.Optional("key_file", json2.String, ""),
+	json2.Map(func(s string) KnownHostsPolicy {
+		return KnownHostsPolicy(s)
+	}, json2.Optional("known_hosts", json2.String, string(KnownHostsStrict))),
+)))
+
 type plugin[Req RequestData, Resp ResponseData] struct {
 	kind            enumElem[Kind]
 	decoderRequest  json2.Decoder[Req]
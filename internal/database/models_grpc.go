@@ -1,6 +1,11 @@
 package database
 
-import json2 "github.com/rprtr258/fun/exp/json"
+import (
+	"time"
+
+	"github.com/rprtr258/fun"
+	json2 "github.com/rprtr258/fun/exp/json"
+)
 
 const KindGRPC Kind = "grpc"
 
@@ -10,30 +15,167 @@ var pluginGRPC = plugin[GRPCRequest, GRPCResponse]{
 	decoderResponseGRPC,
 }
 
+var decoderGRPCProtos = json2.Map(func(m fun.Option[GRPCProtos]) *GRPCProtos {
+	if !m.Valid {
+		return nil
+	}
+	return &m.Value
+}, json2.Nullable(json2.Map3(
+	func(importPaths, files, protosets []string) GRPCProtos {
+		return GRPCProtos{importPaths, files, protosets}
+	},
+	json2.Optional("import_paths", json2.List(json2.String), nil),
+	json2.Optional("files", json2.List(json2.String), nil),
+	json2.Optional("protosets", json2.List(json2.String), nil),
+)))
+
+var decoderGRPCTLS = json2.Map(func(m fun.Option[GRPCTLS]) *GRPCTLS {
+	if !m.Valid {
+		return nil
+	}
+	return &m.Value
+}, json2.Nullable(json2.Map5(
+	func(caFile, certFile, keyFile, serverName string, insecure bool) GRPCTLS {
+		return GRPCTLS{caFile, certFile, keyFile, serverName, insecure}
+	},
+	json2.Optional("ca_file", json2.String, ""),
+	json2.Optional("cert_file", json2.String, ""),
+	json2.Optional("key_file", json2.String, ""),
+	json2.Optional("server_name", json2.String, ""),
+	json2.Optional("insecure", json2.Bool, false),
+)))
+
 var decoderRequestGRPC = json2.Map4(
-	func(target, method, payload string, metadata []KV) GRPCRequest {
-		return GRPCRequest{target, method, payload, metadata}
+	func(req GRPCRequest, protocol GRPCProtocol, protos *GRPCProtos, conn GRPCRequest) GRPCRequest {
+		req.Protocol = protocol
+		req.Protos = protos
+		req.TLS = conn.TLS
+		req.Authority = conn.Authority
+		req.MaxRecvSize = conn.MaxRecvSize
+		req.MaxSendSize = conn.MaxSendSize
+		req.Deadline = conn.Deadline
+		return req
 	},
-	json2.Optional("target", json2.String, ""),
-	json2.Optional("method", json2.String, ""),
-	json2.Optional("payload", json2.String, "{}"),
-	json2.Optional("metadata", decoderKVs, nil),
+	json2.Map5(
+		func(target, method, payload string, metadata []KV, ssh *SSHTunnel) GRPCRequest {
+			return GRPCRequest{Target: target, Method: method, Payload: payload, Metadata: metadata, SSH: ssh}
+		},
+		json2.Optional("target", json2.String, ""),
+		json2.Optional("method", json2.String, ""),
+		json2.Optional("payload", json2.String, "{}"),
+		json2.Optional("metadata", decoderKVs, nil),
+		json2.Optional("ssh", decoderSSHTunnel, nil),
+	),
+	json2.Map(func(s string) GRPCProtocol {
+		return GRPCProtocol(s)
+	}, json2.Optional("protocol", json2.String, string(GRPCProtocolGRPC))),
+	json2.Optional("protos", decoderGRPCProtos, nil),
+	json2.Map5(
+		func(tls *GRPCTLS, authority string, maxRecvSize, maxSendSize int, deadline time.Duration) GRPCRequest {
+			return GRPCRequest{TLS: tls, Authority: authority, MaxRecvSize: maxRecvSize, MaxSendSize: maxSendSize, Deadline: deadline}
+		},
+		json2.Optional("tls", decoderGRPCTLS, nil),
+		json2.Optional("authority", json2.String, ""),
+		json2.Optional("max_recv_size", json2.Int, 0),
+		json2.Optional("max_send_size", json2.Int, 0),
+		json2.Map(func(ns int) time.Duration {
+			return time.Duration(ns)
+		}, json2.Optional("deadline", json2.Int, 0)),
+	),
+)
+
+var decoderGRPCMessage = json2.Map2(
+	func(data string, receivedAt time.Time) GRPCMessage {
+		return GRPCMessage{data, receivedAt}
+	},
+	json2.Required("data", json2.String),
+	json2.Required("received_at", json2.Time),
 )
 
 var decoderResponseGRPC = json2.Map3(
-	func(response string, code int, metadata []KV) GRPCResponse {
-		return GRPCResponse{response, code, metadata}
+	func(resp GRPCResponse, details []string, messages []GRPCMessage) GRPCResponse {
+		resp.Details = details
+		resp.Messages = messages
+		return resp
 	},
-	json2.Required("response", json2.String),
-	json2.Required("code", json2.Int),
-	json2.Optional("metadata", decoderKVs, nil),
+	json2.Map5(
+		func(response string, code int, status string, metadata, trailers []KV) GRPCResponse {
+			return GRPCResponse{Response: response, Code: code, Status: status, Metadata: metadata, Trailers: trailers}
+		},
+		json2.Required("response", json2.String),
+		json2.Required("code", json2.Int),
+		json2.Optional("status", json2.String, ""),
+		json2.Optional("metadata", decoderKVs, nil),
+		json2.Optional("trailers", decoderKVs, nil),
+	),
+	json2.Optional("details", json2.List(json2.String), nil),
+	json2.Optional("messages", json2.List(decoderGRPCMessage), nil),
+)
+
+// GRPCProtos are local descriptors of services
+type GRPCProtos struct {
+	ImportPaths []string `json:"import_paths"`
+	// Files are .proto source files, relative to import paths
+	Files []string `json:"files"`
+	// Protosets are compiled FileDescriptorSet files, like ones produced by protoc --descriptor_set_out
+	Protosets []string `json:"protosets"`
+}
+
+type GRPCTLS struct {
+	// CAFile is used to verify server certificate instead of system roots
+	CAFile string `json:"ca_file"`
+	// CertFile and KeyFile are client certificate and key for mutual TLS
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// ServerName overrides host name used to verify server certificate
+	ServerName string `json:"server_name"`
+	// Insecure disables server certificate verification
+	Insecure bool `json:"insecure"`
+}
+
+type GRPCProtocol string
+
+const (
+	// GRPCProtocolGRPC is native grpc over HTTP/2
+	GRPCProtocolGRPC GRPCProtocol = "grpc"
+	// GRPCProtocolWeb is binary gRPC-Web
+	GRPCProtocolWeb GRPCProtocol = "grpc_web"
+	// GRPCProtocolWebText is base64 encoded gRPC-Web
+	GRPCProtocolWebText GRPCProtocol = "grpc_web_text"
+	// GRPCProtocolConnectJSON is Connect protocol with json messages
+	GRPCProtocolConnectJSON GRPCProtocol = "connect_json"
+	// GRPCProtocolConnectProto is Connect protocol with binary messages
+	GRPCProtocolConnectProto GRPCProtocol = "connect_proto"
 )
 
+var AllGRPCProtocols = []enumElem[GRPCProtocol]{
+	{GRPCProtocolGRPC, "GRPC"},
+	{GRPCProtocolWeb, "GRPC_WEB"},
+	{GRPCProtocolWebText, "GRPC_WEB_TEXT"},
+	{GRPCProtocolConnectJSON, "CONNECT_JSON"},
+	{GRPCProtocolConnectProto, "CONNECT_PROTO"},
+}
+
 type GRPCRequest struct {
-	Target   string `json:"target"`
-	Method   string `json:"method"` // NOTE: fully qualified
-	Payload  string `json:"payload"`
-	Metadata []KV   `json:"metadata"`
+	// Target is host:port for native grpc, or base url like http://host:port/prefix for other protocols
+	Target   string     `json:"target"`
+	Method   string     `json:"method"` // NOTE: fully qualified
+	Payload  string     `json:"payload"`
+	Metadata []KV       `json:"metadata"`
+	SSH      *SSHTunnel `json:"ssh"`
+	// Protocol is transport used for call, server reflection is available only for native grpc
+	Protocol GRPCProtocol `json:"protocol"`
+	// Protos are used to resolve methods instead of server reflection if set
+	Protos *GRPCProtos `json:"protos"`
+	// TLS is used to connect to target, plaintext connection is used if not set
+	TLS *GRPCTLS `json:"tls"`
+	// Authority overrides :authority pseudo header
+	Authority string `json:"authority"`
+	// MaxRecvSize and MaxSendSize limit message size in bytes, grpc defaults are used if zero
+	MaxRecvSize int `json:"max_recv_size"`
+	MaxSendSize int `json:"max_send_size"`
+	// Deadline limits duration of call, no deadline is set if zero
+	Deadline time.Duration `json:"deadline"`
 }
 
 func (GRPCRequest) Kind() Kind { return KindGRPC }
@@ -41,8 +183,21 @@ func (GRPCRequest) Kind() Kind { return KindGRPC }
 type GRPCResponse struct { // TODO: last inserted id on insert
 	Response string `json:"response"`
 	// https://grpc.io/docs/guides/status-codes/#the-full-list-of-status-codes
-	Code     int  `json:"code"`
+	Code int `json:"code"`
+	// Status is name of code, like NOT_FOUND
+	Status string `json:"status"`
+	// Metadata are response headers
 	Metadata []KV `json:"metadata"`
+	Trailers []KV `json:"trailers"`
+	// Details are json encoded google.rpc.Status details of failed call
+	Details []string `json:"details"`
+	// Messages are all received messages, Response holds the only message or json array of them
+	Messages []GRPCMessage `json:"messages"`
+}
+
+type GRPCMessage struct {
+	Data       string    `json:"data"`
+	ReceivedAt time.Time `json:"received_at"`
 }
 
 func (GRPCResponse) isResponseData() Kind { return KindGRPC }
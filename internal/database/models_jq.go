@@ -10,27 +10,121 @@ var pluginJQ = plugin[JQRequest, JQResponse]{
 	decoderResponseJQ,
 }
 
-var decoderRequestJQ = json2.Map2(
-	func(query string, json string) JQRequest {
-		return JQRequest{query, json}
+var decoderJQArg = json2.Map3(
+	func(name, value string, isJSON bool) JQArg {
+		return JQArg{name, value, isJSON}
 	},
-	json2.Optional("query", json2.String, "."),
-	json2.Required("json", json2.String),
+	json2.Required("name", json2.String),
+	json2.Optional("value", json2.String, ""),
+	json2.Optional("json", json2.Bool, false),
 )
 
-var decoderResponseJQ = json2.Map(func(response []string) JQResponse {
-	return JQResponse{response}
-}, json2.Required("response", json2.List(json2.String)))
+var decoderRequestJQ = json2.Map3(
+	func(req JQRequest, inputFormat JQInputFormat, outputFormat JQOutputFormat) JQRequest {
+		req.InputFormat = inputFormat
+		req.OutputFormat = outputFormat
+		return req
+	},
+	json2.Map5(
+		func(query string, json string, input RequestID, inputIndex int, args []JQArg) JQRequest {
+			return JQRequest{Query: query, JSON: json, Input: input, InputIndex: inputIndex, Args: args}
+		},
+		json2.Optional("query", json2.String, "."),
+		json2.Optional("json", json2.String, ""),
+		json2.Map(func(s string) RequestID {
+			return RequestID(s)
+		}, json2.Optional("input", json2.String, "")),
+		json2.Optional("input_index", json2.Int, 0),
+		json2.Optional("args", json2.List(decoderJQArg), nil),
+	),
+	json2.Map(func(s string) JQInputFormat {
+		return JQInputFormat(s)
+	}, json2.Optional("input_format", json2.String, string(JQInputJSON))),
+	json2.Map(func(s string) JQOutputFormat {
+		return JQOutputFormat(s)
+	}, json2.Optional("output_format", json2.String, string(JQOutputJSON))),
+)
+
+var decoderResponseJQ = json2.Map2(func(response []string, format JQOutputFormat) JQResponse {
+	return JQResponse{response, format}
+},
+	json2.Required("response", json2.List(json2.String)),
+	json2.Map(func(s string) JQOutputFormat {
+		return JQOutputFormat(s)
+	}, json2.Optional("format", json2.String, string(JQOutputJSON))),
+)
+
+type JQInputFormat string
+
+const (
+	// JQInputJSON is stream of json values
+	JQInputJSON JQInputFormat = "json"
+	// JQInputYAML is stream of yaml documents
+	JQInputYAML JQInputFormat = "yaml"
+	// JQInputTOML is single toml document
+	JQInputTOML JQInputFormat = "toml"
+	// JQInputCSV is table with header, converted to array of objects with string values
+	JQInputCSV JQInputFormat = "csv"
+	// JQInputXML is single xml document, attributes are prefixed with "@", text of mixed elements is "#text"
+	JQInputXML JQInputFormat = "xml"
+)
+
+var AllJQInputFormats = []enumElem[JQInputFormat]{
+	{JQInputJSON, "JSON"},
+	{JQInputYAML, "YAML"},
+	{JQInputTOML, "TOML"},
+	{JQInputCSV, "CSV"},
+	{JQInputXML, "XML"},
+}
+
+type JQOutputFormat string
+
+const (
+	JQOutputJSON        JQOutputFormat = "json"
+	JQOutputCompactJSON JQOutputFormat = "compact_json"
+	// JQOutputRaw writes strings without quotes, like jq -r
+	JQOutputRaw  JQOutputFormat = "raw"
+	JQOutputYAML JQOutputFormat = "yaml"
+	// JQOutputCSV writes objects and arrays as table rows, objects keys are used as header
+	JQOutputCSV JQOutputFormat = "csv"
+)
+
+var AllJQOutputFormats = []enumElem[JQOutputFormat]{
+	{JQOutputJSON, "JSON"},
+	{JQOutputCompactJSON, "COMPACT_JSON"},
+	{JQOutputRaw, "RAW"},
+	{JQOutputYAML, "YAML"},
+	{JQOutputCSV, "CSV"},
+}
 
 type JQRequest struct {
 	Query string `json:"query"`
-	JSON  string `json:"json"`
+	// JSON is stream of input values, used if Input is not set
+	JSON string `json:"json"`
+	// Input is id of request whose response is used as input
+	Input RequestID `json:"input"`
+	// InputIndex selects response from history of Input, counting back from latest one, which is 0
+	InputIndex int `json:"input_index"`
+	// Args are named variables available in query as $name
+	Args []JQArg `json:"args"`
+	// InputFormat is format of JSON and of bodies of http responses used as input
+	InputFormat  JQInputFormat  `json:"input_format"`
+	OutputFormat JQOutputFormat `json:"output_format"`
+}
+
+type JQArg struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// JSON is whether Value is parsed as json like --argjson does, otherwise it is string like --arg
+	JSON bool `json:"json"`
 }
 
 func (JQRequest) Kind() Kind { return KindJQ }
 
 type JQResponse struct {
-	Response []string `json:"response"`
+	// Response are formatted results, csv output is single table
+	Response []string       `json:"response"`
+	Format   JQOutputFormat `json:"format"`
 }
 
 func (JQResponse) isResponseData() Kind { return KindJQ }
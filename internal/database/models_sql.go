@@ -2,7 +2,9 @@ package database
 
 import (
 	"encoding/base64"
+	"time"
 
+	"github.com/rprtr258/fun"
 	json2 "github.com/rprtr258/fun/exp/json"
 )
 
@@ -14,15 +16,37 @@ var pluginSql = plugin[SQLRequest, SQLResponse]{
 	decoderResponseSQL,
 }
 
-var decoderRequestSQL = json2.Map3(
-	func(dsn string, database Database, query string) SQLRequest {
-		return SQLRequest{dsn, database, query}
+var decoderSQLParam = json2.Map3(
+	func(name string, typ SQLParamType, value string) SQLParam {
+		return SQLParam{name, typ, value}
 	},
-	json2.Optional("dsn", json2.String, ""),
-	json2.Map(func(s string) Database {
-		return Database(s)
-	}, json2.Optional("database", json2.String, "")),
-	json2.Required("query", json2.String),
+	json2.Optional("name", json2.String, ""),
+	json2.Map(func(s string) SQLParamType {
+		return SQLParamType(s)
+	}, json2.Optional("type", json2.String, string(SQLParamTypeString))),
+	json2.Optional("value", json2.String, ""),
+)
+
+var decoderRequestSQL = json2.Map2(
+	func(req SQLRequest, ssh *SSHTunnel) SQLRequest {
+		req.SSH = ssh
+		return req
+	},
+	json2.Map5(
+		func(dsn string, database Database, query string, mode SQLMode, params []SQLParam) SQLRequest {
+			return SQLRequest{dsn, database, query, mode, params, nil}
+		},
+		json2.Optional("dsn", json2.String, ""),
+		json2.Map(func(s string) Database {
+			return Database(s)
+		}, json2.Optional("database", json2.String, "")),
+		json2.Required("query", json2.String),
+		json2.Map(func(s string) SQLMode {
+			return SQLMode(s)
+		}, json2.Optional("mode", json2.String, string(SQLModeAuto))),
+		json2.Optional("params", json2.List(decoderSQLParam), nil),
+	),
+	json2.Optional("ssh", decoderSSHTunnel, nil),
 )
 
 func decoderAny(v any, dest *any) error {
@@ -30,33 +54,94 @@ func decoderAny(v any, dest *any) error {
 	return nil
 }
 
-var decoderResponseSQL = json2.Map3(
-	func(columns []string, types []ColumnType, rows [][]any) SQLResponse {
-		for i, columnType := range types {
-			if columnType != "[]uint8" {
+// decodeBytesColumns decodes base64 encoded []uint8 columns back to strings
+func decodeBytesColumns(types []ColumnType, rows [][]any) {
+	for i, columnType := range types {
+		if columnType != "[]uint8" {
+			continue
+		}
+
+		for j, row := range rows {
+			value, ok := row[i].(string)
+			if !ok {
 				continue
 			}
 
-			for j, row := range rows {
-				value, ok := row[i].(string)
-				if !ok {
-					continue
-				}
-
-				// TODO: parse jsonb
-				decoded, err := base64.StdEncoding.DecodeString(value)
-				if err == nil {
-					rows[j][i] = string(decoded)
-				}
+			// TODO: parse jsonb
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err == nil {
+				rows[j][i] = string(decoded)
 			}
 		}
-		return SQLResponse{columns, types, rows}
+	}
+}
+
+var decoderColumnTypes = json2.List(json2.Map(func(col string) ColumnType {
+	return ColumnType(col)
+}, json2.String))
+
+func decoderInt64Ptr(v any, dest **int64) error {
+	if v == nil {
+		*dest = nil
+		return nil
+	}
+
+	var i int
+	if err := json2.Int(v, &i); err != nil {
+		return err
+	}
+	*dest = fun.Ptr(int64(i))
+	return nil
+}
+
+var decoderSQLResult = json2.Map3(
+	func(
+		query string,
+		table SQLResult,
+		stats SQLResult,
+	) SQLResult {
+		decodeBytesColumns(table.Types, table.Rows)
+		return SQLResult{
+			query,
+			table.Columns, table.Types, table.Rows,
+			stats.RowsAffected, stats.LastInsertID,
+			stats.Duration, stats.Error,
+		}
+	},
+	json2.Required("query", json2.String),
+	json2.Map3(
+		func(columns []string, types []ColumnType, rows [][]any) SQLResult {
+			return SQLResult{Columns: columns, Types: types, Rows: rows}
+		},
+		json2.Optional("columns", json2.List(json2.String), nil),
+		json2.Optional("types", decoderColumnTypes, nil),
+		json2.Optional("rows", json2.List(json2.List(decoderAny)), nil),
+	),
+	json2.Map4(
+		func(rowsAffected, lastInsertID *int64, duration int, err string) SQLResult {
+			return SQLResult{
+				RowsAffected: rowsAffected,
+				LastInsertID: lastInsertID,
+				Duration:     time.Duration(duration),
+				Error:        err,
+			}
+		},
+		json2.Optional("rows_affected", decoderInt64Ptr, nil),
+		json2.Optional("last_insert_id", decoderInt64Ptr, nil),
+		json2.Optional("duration", json2.Int, 0),
+		json2.Optional("error", json2.String, ""),
+	),
+)
+
+var decoderResponseSQL = json2.Map4(
+	func(columns []string, types []ColumnType, rows [][]any, results []SQLResult) SQLResponse {
+		decodeBytesColumns(types, rows)
+		return SQLResponse{columns, types, rows, results}
 	},
 	json2.Required("columns", json2.List(json2.String)),
-	json2.Required("types", json2.List(json2.Map(func(col string) ColumnType {
-		return ColumnType(col)
-	}, json2.String))),
+	json2.Required("types", decoderColumnTypes),
 	json2.Optional("rows", json2.List(json2.List(decoderAny)), [][]any{}),
+	json2.Optional("results", json2.List(decoderSQLResult), nil),
 )
 
 type Database string
@@ -75,10 +160,58 @@ var AllDatabases = []enumElem[Database]{
 	{Clickhouse, "CLICKHOUSE"},
 }
 
+// SQLMode defines how statements of a script are executed
+type SQLMode string
+
+const (
+	// SQLModeAuto runs statements returning rows as queries and others as exec
+	SQLModeAuto  SQLMode = "auto"
+	SQLModeQuery SQLMode = "query"
+	SQLModeExec  SQLMode = "exec"
+)
+
+var AllSQLModes = []enumElem[SQLMode]{
+	{SQLModeAuto, "AUTO"},
+	{SQLModeQuery, "QUERY"},
+	{SQLModeExec, "EXEC"},
+}
+
+type SQLParamType string
+
+const (
+	SQLParamTypeString SQLParamType = "string"
+	SQLParamTypeInt    SQLParamType = "int"
+	SQLParamTypeFloat  SQLParamType = "float"
+	SQLParamTypeBool   SQLParamType = "bool"
+	SQLParamTypeTime   SQLParamType = "time"
+	SQLParamTypeNull   SQLParamType = "null"
+)
+
+var AllSQLParamTypes = []enumElem[SQLParamType]{
+	{SQLParamTypeString, "STRING"},
+	{SQLParamTypeInt, "INT"},
+	{SQLParamTypeFloat, "FLOAT"},
+	{SQLParamTypeBool, "BOOL"},
+	{SQLParamTypeTime, "TIME"},
+	{SQLParamTypeNull, "NULL"},
+}
+
+// SQLParam is bind variable of query. Positional params are bound in order,
+// named ones are used for clickhouse {name:Type} and sqlite :name placeholders.
+type SQLParam struct {
+	Name string       `json:"name"`
+	Type SQLParamType `json:"type"`
+	// Value is string representation of value, environment variables like ${HOME} are expanded
+	Value string `json:"value"`
+}
+
 type SQLRequest struct {
-	DSN      string   `json:"dsn"`
-	Database Database `json:"database"`
-	Query    string   `json:"query"`
+	DSN      string     `json:"dsn"`
+	Database Database   `json:"database"`
+	Query    string     `json:"query"`
+	Mode     SQLMode    `json:"mode"`
+	Params   []SQLParam `json:"params"`
+	SSH      *SSHTunnel `json:"ssh"`
 }
 
 func (SQLRequest) Kind() Kind { return KindSQL }
@@ -99,10 +232,43 @@ const (
 	ColumnTypeBoolean ColumnType = "boolean"
 )
 
-type SQLResponse struct { // TODO: last inserted id on insert
+type SQLExportFormat string
+
+const (
+	SQLExportCSV      SQLExportFormat = "csv"
+	SQLExportTSV      SQLExportFormat = "tsv"
+	SQLExportJSONL    SQLExportFormat = "jsonl"
+	SQLExportMarkdown SQLExportFormat = "markdown"
+	SQLExportInsert   SQLExportFormat = "insert"
+)
+
+var AllSQLExportFormats = []enumElem[SQLExportFormat]{
+	{SQLExportCSV, "CSV"},
+	{SQLExportTSV, "TSV"},
+	{SQLExportJSONL, "JSONL"},
+	{SQLExportMarkdown, "MARKDOWN"},
+	{SQLExportInsert, "INSERT"},
+}
+
+// SQLResult is result of single statement from script
+type SQLResult struct {
+	Query   string       `json:"query"`
+	Columns []string     `json:"columns"`
+	Types   []ColumnType `json:"types"`
+	Rows    [][]any      `json:"rows"`
+	// RowsAffected and LastInsertID are set for exec statements, if supported by driver
+	RowsAffected *int64        `json:"rows_affected"`
+	LastInsertID *int64        `json:"last_insert_id"`
+	Duration     time.Duration `json:"duration"`
+	Error        string        `json:"error,omitempty"`
+}
+
+type SQLResponse struct {
+	// Columns, Types and Rows are copied from last result returning rows
 	Columns []string     `json:"columns"`
 	Types   []ColumnType `json:"types"`
 	Rows    [][]any      `json:"rows"`
+	Results []SQLResult  `json:"results"`
 }
 
 func (SQLResponse) isResponseData() Kind { return KindSQL }
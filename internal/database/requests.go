@@ -38,6 +38,10 @@ func list(fs afero.Fs, prefix string) (Tree, error) {
 	}
 	for _, info := range infos {
 		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") {
+				continue // NOTE: hidden dirs hold workspace data like jq modules, not requests
+			}
+
 			dir := prefix + info.Name()
 			subdir, err := list(fs, dir+"/")
 			if err != nil {
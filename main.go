@@ -52,6 +52,15 @@ func run() error {
 			database.AllKinds,
 			database.AllDatabases,
 			database.AllColumnTypes,
+			database.AllSQLModes,
+			database.AllSQLParamTypes,
+			database.AllSQLExportFormats,
+			database.AllKnownHostsPolicies,
+			database.AllGRPCProtocols,
+			database.AllRedisModes,
+			database.AllRedisDeploymentKinds,
+			database.AllJQInputFormats,
+			database.AllJQOutputFormats,
 		},
 		StartHidden: true,
 	})